@@ -0,0 +1,501 @@
+package securityspy
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// String returns the policy name.
+func (p DeliveryPolicy) String() string {
+	switch p {
+	case DeliverDropNewest:
+		return "drop newest"
+	case DeliverDropOldest:
+		return "drop oldest"
+	case DeliverBlock:
+		return "block"
+	case DeliverCoalesce:
+		return "coalesce"
+	default:
+		return fmt.Sprintf("DeliveryPolicy(%d)", int(p))
+	}
+}
+
+// String returns the order name.
+func (o DeliveryOrder) String() string {
+	switch o {
+	case OrderNone:
+		return "none"
+	case OrderCamera:
+		return "camera"
+	case OrderGlobal:
+		return "global"
+	default:
+		return fmt.Sprintf("DeliveryOrder(%d)", int(o))
+	}
+}
+
+// BindFuncConfig binds a call-back function to an Event like BindFunc, using the delivery
+// policy and queue size in config. Config may be nil. The Binding has the delivery counters,
+// and Binding.Unbind removes it without removing other callbacks for the event type.
+func (e *Events) BindFuncConfig(event EventType, callBack func(Event), config *BindConfig) *Binding {
+	if callBack == nil {
+		return nil
+	}
+
+	return e.bind(event, &Binding{fn: func(_ context.Context, event Event) { callBack(event) }}, config)
+}
+
+// BindFuncContext binds a call-back function like BindFuncConfig. The callback's context
+// ends after config.Timeout, or when Stop is called, or when StopDrain gives up waiting.
+// Config may be nil.
+func (e *Events) BindFuncContext(event EventType, callBack func(context.Context, Event), config *BindConfig) *Binding {
+	if callBack == nil {
+		return nil
+	}
+
+	return e.bind(event, &Binding{fn: callBack}, config)
+}
+
+// BindChanConfig binds a receiving channel to an Event like BindChan, using the delivery
+// policy and queue size in config. Config may be nil. The Binding has the delivery counters,
+// and Binding.Unbind removes it without removing other channels for the event type.
+func (e *Events) BindChanConfig(event EventType, channel chan Event, config *BindConfig) *Binding {
+	if channel == nil {
+		return nil
+	}
+
+	return e.bind(event, &Binding{channel: channel}, config)
+}
+
+// BindingStats returns the delivery counters for every binding, sorted by event type.
+func (e *Events) BindingStats() []BindingStats {
+	bindings := e.allBindings()
+	stats := make([]BindingStats, 0, len(bindings))
+
+	for _, binding := range bindings {
+		stats = append(stats, binding.Stats())
+	}
+
+	slices.SortStableFunc(stats, func(a, b BindingStats) int { return cmp.Compare(a.Event, b.Event) })
+
+	return stats
+}
+
+// Unbind removes this binding only, leaving other callbacks and channels for its event
+// type in place. Events still queued for it are discarded; channels are not closed,
+// except those from Subscribe. Unbinding twice, or a nil binding, does nothing.
+func (b *Binding) Unbind() {
+	if b == nil || b.events == nil {
+		return
+	}
+
+	b.events.unbind(func(binding *Binding) bool { return binding == b })
+}
+
+// Stats returns the binding's delivery counters.
+func (b *Binding) Stats() BindingStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	stats.Queued = len(b.queue)
+
+	return stats
+}
+
+// bind sets up a binding, starts its go routine and adds it to the bindings.
+func (e *Events) bind(event EventType, binding *Binding, config *BindConfig) *Binding {
+	if config != nil {
+		binding.config = *config
+	}
+
+	if binding.config.QueueSize <= 0 {
+		binding.config.QueueSize = DefaultBindQueue
+	}
+
+	binding.sem = make(chan struct{}, maxCallbackWorkers)
+	binding.event = event
+	binding.events = e
+	binding.stats = BindingStats{
+		Event:   event,
+		Channel: binding.isChan(),
+		Policy:  binding.config.Policy,
+		Order:   binding.config.Order,
+	}
+	binding.ready = make(chan struct{}, 1)
+	binding.space = make(chan struct{}, 1)
+	binding.stop = make(chan struct{})
+	binding.done = make(chan struct{})
+
+	go binding.run()
+
+	e.binds.Lock()
+	defer e.binds.Unlock()
+
+	if e.bindings == nil {
+		e.bindings = make(map[EventType][]*Binding)
+	}
+
+	e.bindings[event] = append(e.bindings[event], binding)
+
+	return binding
+}
+
+// unbind removes the bindings that match and stops their go routines. Subscription
+// channels are closed; other channels are not. Returns the removed bindings.
+func (e *Events) unbind(match func(*Binding) bool) []*Binding {
+	e.binds.Lock()
+
+	var removed []*Binding
+
+	for event, bindings := range e.bindings {
+		kept := make([]*Binding, 0, len(bindings))
+
+		for _, binding := range bindings {
+			if match(binding) {
+				removed = append(removed, binding)
+			} else {
+				kept = append(kept, binding)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(e.bindings, event)
+		} else {
+			e.bindings[event] = kept
+		}
+	}
+
+	e.binds.Unlock()
+
+	for _, binding := range removed {
+		binding.close()
+
+		if !binding.sub {
+			continue
+		}
+
+		binding.mu.Lock()
+		release := binding.release
+		binding.mu.Unlock()
+
+		if release != nil {
+			release()
+		}
+
+		close(binding.channel)
+	}
+
+	return removed
+}
+
+// allBindings returns every binding.
+func (e *Events) allBindings() []*Binding {
+	e.binds.RLock()
+	defer e.binds.RUnlock()
+
+	var all []*Binding
+	for _, bindings := range e.bindings {
+		all = append(all, bindings...)
+	}
+
+	return all
+}
+
+// bindingsFor returns the bindings that get an event type. Callbacks bound to
+// EventUnknownEvent also get event types that have no callbacks of their own.
+func (e *Events) bindingsFor(eventType EventType) []*Binding {
+	e.binds.RLock()
+	defer e.binds.RUnlock()
+
+	bindings := slices.Clone(e.bindings[eventType])
+
+	if eventType != EventUnknownEvent && !slices.ContainsFunc(bindings, (*Binding).isFunc) {
+		for _, binding := range e.bindings[EventUnknownEvent] {
+			if binding.isFunc() {
+				bindings = append(bindings, binding)
+			}
+		}
+	}
+
+	return append(bindings, e.bindings[EventAllEvents]...)
+}
+
+// dispatch queues an event on every binding for its type.
+func (e *Events) dispatch(ctx context.Context, event *Event) {
+	for _, binding := range e.bindingsFor(event.Type) {
+		if binding.filter == nil || binding.filter(event) {
+			binding.offer(ctx, *event)
+		}
+	}
+}
+
+// reportDrops emits an EventDeliveryDropped event if any binding dropped events since the last report.
+func (e *Events) reportDrops(ctx context.Context) {
+	report := &DropReport{}
+
+	for _, binding := range e.allBindings() {
+		binding.mu.Lock()
+
+		if dropped := binding.stats.Dropped - binding.reported; dropped > 0 {
+			binding.reported = binding.stats.Dropped
+			report.Dropped += dropped
+			stats := binding.stats
+			stats.Queued = len(binding.queue)
+			report.Bindings = append(report.Bindings, stats)
+		}
+
+		binding.mu.Unlock()
+	}
+
+	if report.Dropped == 0 {
+		return
+	}
+
+	now := time.Now().Round(time.Second)
+	msg := fmt.Sprintf("%d events dropped by %d bindings", report.Dropped, len(report.Bindings))
+
+	e.dispatch(ctx, &Event{
+		Time:    now,
+		When:    now,
+		ID:      -9994, //nolint:mnd // library events use fixed negative IDs.
+		Type:    EventDeliveryDropped,
+		Msg:     string(EventDeliveryDropped) + " " + msg,
+		Dropped: report,
+	})
+}
+
+func (b *Binding) isFunc() bool {
+	return b.fn != nil
+}
+
+func (b *Binding) isChan() bool {
+	return b.channel != nil
+}
+
+// offer queues an event, applying the delivery policy if the queue is full.
+// DeliverBlock waits for room until ctx ends or the binding is removed.
+func (b *Binding) offer(ctx context.Context, event Event) {
+	b.mu.Lock()
+
+	for b.config.Policy == DeliverBlock && len(b.queue) >= b.config.QueueSize {
+		b.mu.Unlock()
+
+		select {
+		case <-b.space:
+		case <-b.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		b.mu.Lock()
+	}
+
+	switch {
+	case b.config.Policy == DeliverCoalesce && b.coalesce(event):
+		b.stats.Dropped++
+	case len(b.queue) < b.config.QueueSize:
+		b.queue = append(b.queue, event)
+	case b.config.Policy == DeliverDropNewest:
+		b.stats.Dropped++
+	default: // DeliverDropOldest and DeliverCoalesce.
+		b.queue = append(b.queue[1:], event)
+		b.stats.Dropped++
+	}
+
+	b.mu.Unlock()
+
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// coalesce replaces a queued event with the same type and camera. Call with the lock held.
+func (b *Binding) coalesce(event Event) bool {
+	for idx, queued := range b.queue {
+		if queued.Type == event.Type && cameraNumber(queued.Camera) == cameraNumber(event.Camera) {
+			b.queue[idx] = event
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// run delivers queued events until the binding is removed.
+func (b *Binding) run() {
+	defer close(b.done)
+	defer b.closeLanes()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-b.ready:
+		}
+
+		for !b.stopped() {
+			event, ok := b.pop()
+			if !ok {
+				break
+			}
+
+			if !b.deliver(event) {
+				return
+			}
+		}
+	}
+}
+
+// stopped returns true once the binding is removed.
+func (b *Binding) stopped() bool {
+	select {
+	case <-b.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// pop removes the next event from the queue.
+func (b *Binding) pop() (Event, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.queue) == 0 {
+		return Event{}, false
+	}
+
+	event := b.queue[0]
+	b.queue = b.queue[1:]
+	b.events.inflight.Add(1)
+
+	select {
+	case b.space <- struct{}{}:
+	default:
+	}
+
+	return event, true
+}
+
+// deliver sends an event to the channel or hands it to the callback, as the delivery
+// order says. Returns false if the binding was removed while waiting.
+// pop counted the event as in flight; whoever finishes with it uncounts it.
+func (b *Binding) deliver(event Event) bool {
+	switch {
+	case b.isChan():
+		defer b.events.inflight.Add(-1)
+
+		select {
+		case b.channel <- event:
+		case <-b.stop:
+			return false
+		}
+	case b.config.Order == OrderGlobal:
+		b.delivered()
+		b.call(event)
+
+		return true
+	case b.config.Order == OrderCamera:
+		select {
+		case b.lane(cameraNumber(event.Camera)) <- event:
+			return true // The lane counts it.
+		case <-b.stop:
+			b.events.inflight.Add(-1)
+			return false
+		}
+	default:
+		select {
+		case b.sem <- struct{}{}:
+			go func() {
+				defer func() { <-b.sem }()
+
+				b.call(event)
+			}()
+		case <-b.stop:
+			b.events.inflight.Add(-1)
+			return false
+		}
+	}
+
+	b.delivered()
+
+	return true
+}
+
+// lane returns the OrderCamera queue for a camera, starting its go routine the first time.
+func (b *Binding) lane(camera int) chan Event {
+	if b.lanes == nil {
+		b.lanes = make(map[int]chan Event)
+	}
+
+	if lane, ok := b.lanes[camera]; ok {
+		return lane
+	}
+
+	lane := make(chan Event, b.config.QueueSize)
+	b.lanes[camera] = lane
+
+	b.laneWG.Go(func() {
+		for {
+			select {
+			case <-b.stop:
+				return
+			case event := <-lane:
+				if b.stopped() {
+					b.events.inflight.Add(-1)
+					return
+				}
+
+				b.delivered()
+				b.call(event)
+			}
+		}
+	})
+
+	return lane
+}
+
+func (b *Binding) delivered() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stats.Delivered++
+}
+
+// close stops the binding's go routines. Queued events are discarded. A channel
+// binding is waited for, so the channel may be closed next. Callback bindings are
+// not: a callback may unbind itself, and its go routines return on their own.
+func (b *Binding) close() {
+	close(b.stop)
+
+	if b.isChan() {
+		<-b.done
+	}
+}
+
+// closeLanes waits for the OrderCamera go routines to return and uncounts the events
+// left in their queues. Only the binding's go routine calls this, as it returns.
+func (b *Binding) closeLanes() {
+	b.laneWG.Wait()
+
+	for _, lane := range b.lanes {
+		b.events.inflight.Add(-int64(len(lane)))
+	}
+}
+
+// cameraNumber returns a camera's number, or -1 for events without a camera.
+func cameraNumber(camera *Camera) int {
+	if camera == nil {
+		return -1
+	}
+
+	return camera.Number
+}
@@ -0,0 +1,261 @@
+package securityspy_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+func TestBindingDeliveryPolicies(t *testing.T) {
+	t.Parallel()
+
+	for policy, want := range map[securityspy.DeliveryPolicy][]string{
+		securityspy.DeliverDropNewest: {"0", "1", "2"},
+		securityspy.DeliverDropOldest: {"0", "3", "4"},
+		securityspy.DeliverBlock:      {"0", "1", "2", "3", "4"},
+		securityspy.DeliverCoalesce:   {"0", "4"},
+	} {
+		t.Run(policy.String(), func(t *testing.T) {
+			t.Parallel()
+
+			fake := securityspytest.NewServer()
+			t.Cleanup(fake.Close)
+
+			sspy, err := securityspy.New(fake.Config())
+			require.NoError(t, err)
+
+			events := make(chan securityspy.Event) // Unbuffered, and nobody reads it yet.
+			dropped := make(chan securityspy.Event, 1)
+			seen := make(chan securityspy.Event, 10)
+			binding := sspy.Events.BindChanConfig(securityspy.EventStreamCustom, events,
+				&securityspy.BindConfig{Policy: policy, QueueSize: 2})
+			sspy.Events.BindChan(securityspy.EventDeliveryDropped, dropped)
+			sspy.Events.BindChan(securityspy.EventStreamCustom, seen)
+			sspy.Events.Watch(10*time.Millisecond, false)
+			t.Cleanup(func() { sspy.Events.Stop(false) })
+
+			// The first event leaves the queue and waits on the channel.
+			sspy.Events.Custom(3, "0")
+			<-seen
+			require.Eventually(t, func() bool { return binding.Stats().Queued == 0 }, time.Second, time.Millisecond)
+
+			go func() {
+				for _, msg := range []string{"1", "2", "3", "4"} {
+					sspy.Events.Custom(3, msg)
+				}
+			}()
+
+			if policy != securityspy.DeliverBlock {
+				require.Eventually(t, func() bool {
+					stats := binding.Stats()
+					return stats.Queued+stats.Dropped == 4
+				}, time.Second, time.Millisecond)
+			}
+
+			for _, msg := range want {
+				require.Equal(t, "CUSTOM "+msg, (<-events).Msg)
+			}
+
+			require.Eventually(t, func() bool { return binding.Stats().Delivered == len(want) }, time.Second, time.Millisecond)
+
+			stats := binding.Stats()
+			require.Equal(t, 5-len(want), stats.Dropped)
+			require.Equal(t, []securityspy.BindingStats{stats}, sspy.Events.BindingStats()[:1], "CUSTOM sorts first")
+
+			if stats.Dropped == 0 {
+				return
+			}
+
+			select {
+			case event := <-dropped:
+				require.Equal(t, stats.Dropped, event.Dropped.Dropped)
+				require.Len(t, event.Dropped.Bindings, 1)
+				require.Equal(t, policy, event.Dropped.Bindings[0].Policy)
+			case <-time.After(3 * securityspy.DropReportInterval):
+				t.Fatal("no drop report")
+			}
+		})
+	}
+}
+
+func TestBindingOrderCamera(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	var (
+		mu   sync.Mutex
+		got  []string
+		done = make(chan struct{})
+	)
+
+	sspy.Events.BindFuncConfig(securityspy.EventAllEvents, func(event securityspy.Event) {
+		if event.Camera == nil {
+			return
+		}
+
+		if event.Camera.Number == 3 {
+			time.Sleep(50 * time.Millisecond) // Slow camera 3 down, so camera 2 passes it.
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if got = append(got, fmt.Sprint(event.Camera.Number, " ", event.Type)); len(got) == 4 {
+			close(done)
+		}
+	}, &securityspy.BindConfig{Order: securityspy.OrderCamera})
+
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, sspy.Events.WaitConnected(ctx))
+
+	fake.PushEvent(3, "TRIGGER_M", "1")
+	fake.PushEvent(3, "CLASSIFY", "HUMAN", "90")
+	fake.PushEvent(3, "MOTION_END")
+	fake.PushEvent(2, "ONLINE")
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("callbacks did not finish")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Equal(t, []string{"2 ONLINE", "3 TRIGGER_M", "3 CLASSIFY", "3 MOTION_END"}, got,
+		"each camera's events arrive in order, and cameras do not wait for each other")
+}
+
+func TestBindingCoalesceKeepsPlace(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	events := make(chan securityspy.Event) // Unbuffered, and nobody reads it yet.
+	seen := make(chan securityspy.Event, 10)
+	binding := sspy.Events.BindChanConfig(securityspy.EventStreamCustom, events,
+		&securityspy.BindConfig{Policy: securityspy.DeliverCoalesce, QueueSize: 3})
+	sspy.Events.BindChan(securityspy.EventStreamCustom, seen)
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	sspy.Events.Custom(1, "0") // Leaves the queue and waits on the channel.
+	<-seen
+	require.Eventually(t, func() bool { return binding.Stats().Queued == 0 }, time.Second, time.Millisecond)
+
+	for camera, msg := range []string{"a1", "b1", "a2"} {
+		sspy.Events.Custom(camera%2+2, msg) // Cameras 2, 3, 2.
+		<-seen
+	}
+
+	require.Eventually(t, func() bool { return binding.Stats().Dropped == 1 }, time.Second, time.Millisecond)
+
+	for _, msg := range []string{"0", "a2", "b1"} {
+		require.Equal(t, "CUSTOM "+msg, (<-events).Msg, "the new event takes the replaced event's place")
+	}
+}
+
+func TestBindingUnbind(t *testing.T) {
+	t.Parallel()
+
+	sspy := watchFake(t)
+	first := make(chan securityspy.Event, 10)
+	second := make(chan securityspy.Event, 10)
+	binding := sspy.Events.BindChanConfig(securityspy.EventStreamCustom, first, nil)
+	sspy.Events.BindChan(securityspy.EventStreamCustom, second)
+
+	sspy.Events.Custom(3, "both")
+	require.Equal(t, "CUSTOM both", (<-first).Msg)
+	require.Equal(t, "CUSTOM both", (<-second).Msg)
+
+	binding.Unbind()
+	binding.Unbind()
+	require.Len(t, sspy.Events.BindingStats(), 1)
+
+	sspy.Events.Custom(3, "second")
+	require.Equal(t, "CUSTOM second", (<-second).Msg)
+	require.Empty(t, first, "the unbound channel gets nothing")
+
+	(*securityspy.Binding)(nil).Unbind()
+}
+
+func TestBindingUnbindFromCallback(t *testing.T) {
+	t.Parallel()
+
+	for _, order := range []securityspy.DeliveryOrder{securityspy.OrderGlobal, securityspy.OrderCamera} {
+		t.Run(order.String(), func(t *testing.T) {
+			t.Parallel()
+
+			sspy := watchFake(t)
+			done := make(chan struct{})
+
+			sspy.Events.BindFuncConfig(securityspy.EventStreamCustom, func(securityspy.Event) {
+				sspy.Events.UnbindFunc(securityspy.EventStreamCustom)
+				close(done)
+			}, &securityspy.BindConfig{Order: order})
+
+			sspy.Events.Custom(3, "unbind")
+
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("a callback that unbinds its own event type deadlocked")
+			}
+
+			require.Empty(t, sspy.Events.BindingStats())
+		})
+	}
+}
+
+func TestBindingSlowOrderNone(t *testing.T) {
+	t.Parallel()
+
+	sspy := watchFake(t)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	stuck := sspy.Events.BindFuncConfig(securityspy.EventStreamCustom, func(securityspy.Event) {
+		<-release
+	}, nil)
+
+	fast := make(chan string, 1)
+	sspy.Events.BindFunc(securityspy.EventStreamCustom, func(event securityspy.Event) {
+		if event.Msg == "CUSTOM last" {
+			fast <- event.Msg
+		}
+	})
+
+	for range 40 { // More than one binding's callback workers.
+		sspy.Events.Custom(3, "stuck")
+	}
+
+	sspy.Events.Custom(3, "last")
+
+	select {
+	case msg := <-fast:
+		require.Equal(t, "CUSTOM last", msg)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stuck callbacks on one binding held up another binding")
+	}
+
+	require.Positive(t, stuck.Stats().Queued, "the stuck binding is out of workers")
+}
@@ -0,0 +1,114 @@
+package securityspy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBindQueue is the number of events a binding holds for its callback or
+	// channel when BindConfig.QueueSize is 0.
+	DefaultBindQueue = 1000
+	// DropReportInterval is the least time between EventDeliveryDropped events.
+	DropReportInterval = time.Second
+)
+
+// DeliveryPolicy decides what a binding does with a new event when its queue is full.
+type DeliveryPolicy int
+
+// These are the delivery policies. The zero value is DeliverDropNewest.
+const (
+	// DeliverDropNewest drops the new event. This is how bindings behaved before they had queues.
+	DeliverDropNewest DeliveryPolicy = iota
+	// DeliverDropOldest drops the oldest queued event to make room for the new one.
+	DeliverDropOldest
+	// DeliverBlock waits for room in the queue. This holds up every other binding
+	// and, once the event buffer fills, reading from the event stream.
+	DeliverBlock
+	// DeliverCoalesce keeps only the newest queued event of each type for each camera:
+	// a new event takes the place in the queue of a queued one from the same camera with
+	// the same type, and the queued one counts as dropped. If no queued event matches, the oldest one is dropped.
+	DeliverCoalesce
+)
+
+// DeliveryOrder decides whether a callback gets events one at a time, in stream order.
+// Channels always get events in stream order.
+type DeliveryOrder int
+
+// These are the delivery orders. The zero value is OrderNone.
+const (
+	// OrderNone runs the callback in a new go routine for each event, so events may
+	// reach it out of order and at the same time. This is how BindFunc always worked.
+	// Each binding runs up to 32 callbacks at once, so stuck callbacks hold up only their own binding.
+	OrderNone DeliveryOrder = iota
+	// OrderCamera runs the callback for one event from a camera at a time, in stream
+	// order. Events from different cameras run at the same time. Events without a
+	// camera are ordered together, as if they came from one more camera.
+	OrderCamera
+	// OrderGlobal runs the callback for one event at a time, in stream order.
+	OrderGlobal
+)
+
+// BindConfig is optional input for Events.BindFuncConfig and Events.BindChanConfig.
+type BindConfig struct {
+	Policy    DeliveryPolicy
+	Order     DeliveryOrder // Callbacks only.
+	QueueSize int           // 0 uses DefaultBindQueue. OrderCamera also queues this many events per camera.
+	// Timeout ends the context passed to a BindFuncContext callback. A callback that runs
+	// longer than Timeout, with or without a context, is reported with EventCallbackTimeout
+	// as soon as Timeout passes, even if it never returns.
+	Timeout time.Duration
+}
+
+// Binding is one callback or channel bound to an event type. Each binding has its
+// own queue, filled by the event watcher and emptied into the callback or channel
+// by its own go routine, so one slow consumer does not hold up the others.
+type Binding struct {
+	events   *Events
+	event    EventType
+	config   BindConfig
+	fn       func(context.Context, Event)
+	channel  chan Event
+	filter   func(*Event) bool // Subscriptions only get events that pass.
+	sub      bool              // Made by Subscribe. Its channel closes when it is removed.
+	sem      chan struct{}     // OrderNone callback workers, for this binding only.
+	ready    chan struct{}     // Signals the worker that the queue has events.
+	space    chan struct{}     // Signals DeliverBlock that the queue has room.
+	stop     chan struct{}
+	done     chan struct{}
+	lanes    map[int]chan Event // OrderCamera queues, by camera number. Only the binding's go routine uses this.
+	laneWG   sync.WaitGroup
+	mu       sync.Mutex // Protects the fields below.
+	queue    []Event
+	stats    BindingStats
+	reported int         // Dropped count in the last EventDeliveryDropped event.
+	release  func() bool // Stops the subscription's context.AfterFunc.
+}
+
+// BindingStats are the delivery counters for one binding, from Binding.Stats or Events.BindingStats.
+type BindingStats struct {
+	Event     EventType
+	Channel   bool // The binding sends to a channel, not a callback.
+	Policy    DeliveryPolicy
+	Order     DeliveryOrder
+	Delivered int // Events handed to the callback or sent to the channel.
+	Dropped   int // Events dropped because the queue was full, or replaced by DeliverCoalesce.
+	Queued    int // Events waiting in the queue now.
+	Panics    int // Callback runs that panicked.
+	Timeouts  int // Callback runs that took longer than BindConfig.Timeout.
+}
+
+// CallbackFailure is attached to EventCallbackPanic and EventCallbackTimeout events.
+type CallbackFailure struct {
+	Event   Event         // The event the callback was running with, or an incident's last event.
+	Panic   any           // The value passed to panic. Nil for timeouts.
+	Stack   []byte        // Stack trace of the panic. Nil for timeouts.
+	Runtime time.Duration // How long the callback ran, or had run when it timed out.
+}
+
+// DropReport is attached to EventDeliveryDropped events.
+type DropReport struct {
+	Dropped  int            // Events dropped since the last report, across all bindings.
+	Bindings []BindingStats // Bindings that dropped events since the last report.
+}
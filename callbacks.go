@@ -0,0 +1,177 @@
+package securityspy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// drainPoll is how often StopDrain checks whether the bindings are empty.
+const drainPoll = 10 * time.Millisecond
+
+// StopDrain stops the watcher like Stop, but waits for bindings to deliver the events
+// already queued, and for running callbacks to return, before it closes channels.
+// It gives up when ctx ends and returns the context error, then cancels the contexts
+// passed to BindFuncContext callbacks and closes channels anyway if closeChans is true.
+// Channels with no reader keep the bindings from draining, so pass a ctx that ends.
+func (e *Events) StopDrain(ctx context.Context, closeChans bool) error {
+	e.stopWatch()
+
+	err := e.drain(ctx, drainPoll)
+
+	e.cancelCallbacks()
+
+	if closeChans {
+		e.closeChans()
+	}
+
+	return err
+}
+
+// drain waits until every binding's queue is empty and no events are in flight.
+func (e *Events) drain(ctx context.Context, poll time.Duration) error {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for !e.drained() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("draining events: %d in flight: %w", e.inflight.Load(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+func (e *Events) drained() bool {
+	if e.inflight.Load() > 0 {
+		return false
+	}
+
+	for _, binding := range e.allBindings() {
+		if binding.Stats().Queued > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// callbackContext returns the context for one callback run.
+func (e *Events) callbackContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	e.mu.Lock()
+	if e.callbackCtx == nil {
+		e.callbackCtx, e.callbackCancel = context.WithCancel(context.Background())
+	}
+
+	base := e.callbackCtx
+	e.mu.Unlock()
+
+	if timeout > 0 {
+		return context.WithTimeout(base, timeout)
+	}
+
+	return context.WithCancel(base)
+}
+
+// cancelCallbacks ends the context of every running callback. Later runs get a new one.
+func (e *Events) cancelCallbacks() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.callbackCancel != nil {
+		e.callbackCancel()
+	}
+
+	e.callbackCtx, e.callbackCancel = nil, nil
+}
+
+// call runs the callback with an event. Panics are recovered and reported with
+// EventCallbackPanic. Runs longer than the timeout are reported with EventCallbackTimeout
+// once the timeout passes, so callbacks that hang and ignore their context are reported too.
+func (b *Binding) call(event Event) {
+	defer b.events.inflight.Add(-1)
+
+	start := time.Now()
+
+	ctx, cancel := b.events.callbackContext(b.config.Timeout)
+	defer cancel()
+
+	var once sync.Once
+
+	timedOut := func() {
+		once.Do(func() { b.timedOut(event, time.Since(start)) })
+	}
+
+	if b.config.Timeout > 0 {
+		timer := time.AfterFunc(b.config.Timeout, timedOut)
+		defer timer.Stop()
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			b.mu.Lock()
+			b.stats.Panics++
+			b.mu.Unlock()
+
+			b.events.callbackPanicked(event, recovered, start)
+		}
+	}()
+
+	b.fn(ctx, event)
+
+	// The timer may not have fired yet when a callback returns as its context ends.
+	if b.config.Timeout > 0 && (errors.Is(ctx.Err(), context.DeadlineExceeded) || time.Since(start) > b.config.Timeout) {
+		timedOut()
+	}
+}
+
+// timedOut counts and reports a callback run that took longer than the timeout.
+func (b *Binding) timedOut(event Event, runtime time.Duration) {
+	b.mu.Lock()
+	b.stats.Timeouts++
+	b.mu.Unlock()
+
+	b.events.callbackFailed(EventCallbackTimeout, &CallbackFailure{Event: event, Runtime: runtime})
+}
+
+// callbackPanicked reports a panic recovered from a callback run with an event.
+func (e *Events) callbackPanicked(event Event, recovered any, start time.Time) {
+	e.callbackFailed(EventCallbackPanic, &CallbackFailure{
+		Event:   event,
+		Panic:   recovered,
+		Stack:   debug.Stack(),
+		Runtime: time.Since(start),
+	})
+}
+
+// callbackFailed emits an EventCallbackPanic or EventCallbackTimeout event.
+// Failures while handling those two events are not reported, so they cannot loop.
+func (e *Events) callbackFailed(eventType EventType, failure *CallbackFailure) {
+	if failure.Event.Type == EventCallbackPanic || failure.Event.Type == EventCallbackTimeout {
+		return
+	}
+
+	now := time.Now().Round(time.Second)
+	msg := fmt.Sprintf("callback for %s event %d panicked: %v", failure.Event.Type, failure.Event.ID, failure.Panic)
+	eventID := -9993
+
+	if eventType == EventCallbackTimeout {
+		msg = fmt.Sprintf("callback for %s event %d ran for %v", failure.Event.Type, failure.Event.ID, failure.Runtime)
+		eventID = -9992
+	}
+
+	e.enqueue(&Event{
+		Time:    now,
+		When:    now,
+		ID:      eventID,
+		Type:    eventType,
+		Msg:     string(eventType) + " " + msg,
+		Camera:  failure.Event.Camera,
+		Failure: failure,
+	})
+}
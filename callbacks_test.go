@@ -0,0 +1,150 @@
+package securityspy_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+)
+
+func TestCallbackPanicAndTimeout(t *testing.T) {
+	t.Parallel()
+
+	sspy := watchFake(t)
+	failures := make(chan securityspy.Event, 10)
+	sspy.Events.BindChan(securityspy.EventCallbackPanic, failures)
+	sspy.Events.BindChan(securityspy.EventCallbackTimeout, failures)
+
+	panicky := sspy.Events.BindFuncConfig(securityspy.EventStreamCustom, func(event securityspy.Event) {
+		if event.Msg == "CUSTOM boom" {
+			panic("boom")
+		}
+	}, nil)
+
+	ctxErr := make(chan error, 1)
+	slow := sspy.Events.BindFuncContext(securityspy.EventStreamCustom, func(ctx context.Context, event securityspy.Event) {
+		if event.Msg == "CUSTOM slow" {
+			<-ctx.Done()
+			ctxErr <- ctx.Err()
+		}
+	}, &securityspy.BindConfig{Timeout: 20 * time.Millisecond})
+
+	sspy.Events.Custom(3, "boom")
+
+	event := nextEvent(t, failures)
+	require.Equal(t, securityspy.EventCallbackPanic, event.Type)
+	require.Equal(t, "boom", event.Failure.Panic)
+	require.Contains(t, string(event.Failure.Stack), "callbacks_test.go")
+	require.Equal(t, "CUSTOM boom", event.Failure.Event.Msg)
+	require.Equal(t, 3, event.Camera.Number)
+	require.Equal(t, 1, panicky.Stats().Panics)
+
+	sspy.Events.Custom(3, "slow")
+	require.ErrorIs(t, nextEvent(t, ctxErr), context.DeadlineExceeded)
+
+	event = nextEvent(t, failures)
+	require.Equal(t, securityspy.EventCallbackTimeout, event.Type)
+	require.Nil(t, event.Failure.Panic)
+	require.GreaterOrEqual(t, event.Failure.Runtime, 20*time.Millisecond)
+	require.Equal(t, 1, slow.Stats().Timeouts)
+}
+
+func TestCallbackHangReported(t *testing.T) {
+	t.Parallel()
+
+	sspy := watchFake(t)
+	failures := make(chan securityspy.Event, 10)
+	sspy.Events.BindChan(securityspy.EventCallbackTimeout, failures)
+
+	release := make(chan struct{})
+	hung := sspy.Events.BindFuncConfig(securityspy.EventStreamCustom, func(securityspy.Event) {
+		<-release // Ignores the timeout.
+	}, &securityspy.BindConfig{Timeout: 20 * time.Millisecond})
+
+	sspy.Events.Custom(3, "hang")
+
+	event := nextEvent(t, failures)
+	require.Equal(t, "CUSTOM hang", event.Failure.Event.Msg)
+	require.GreaterOrEqual(t, event.Failure.Runtime, 20*time.Millisecond)
+	require.Equal(t, 1, hung.Stats().Timeouts, "reported while the callback still runs")
+
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, 1, hung.Stats().Timeouts, "reported once")
+}
+
+// nextEvent receives from a channel, failing the test if nothing arrives.
+func nextEvent[T any](t *testing.T, channel <-chan T) T {
+	t.Helper()
+
+	select {
+	case value := <-channel:
+		return value
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting on channel")
+	}
+
+	var zero T
+
+	return zero
+}
+
+func TestStopDrain(t *testing.T) {
+	t.Parallel()
+
+	sspy := watchFake(t)
+
+	var handled atomic.Int32
+
+	sspy.Events.BindFuncConfig(securityspy.EventStreamCustom, func(securityspy.Event) {
+		time.Sleep(10 * time.Millisecond)
+		handled.Add(1)
+	}, &securityspy.BindConfig{Order: securityspy.OrderGlobal})
+
+	events := make(chan securityspy.Event, 10)
+	sspy.Events.BindChan(securityspy.EventStreamCustom, events)
+
+	for range 5 {
+		sspy.Events.Custom(3, "work")
+	}
+
+	require.Eventually(t, func() bool { return len(events) == 5 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	require.NoError(t, sspy.Events.StopDrain(ctx, true))
+	require.Equal(t, int32(5), handled.Load(), "queued callbacks finish before StopDrain returns")
+	require.Len(t, events, 5)
+
+	for range 5 {
+		<-events
+	}
+
+	_, ok := <-events
+	require.False(t, ok, "channels are closed after the drain")
+}
+
+func TestStopDrainDeadline(t *testing.T) {
+	t.Parallel()
+
+	sspy := watchFake(t)
+	ctxErr := make(chan error, 1)
+
+	sspy.Events.BindFuncContext(securityspy.EventStreamCustom, func(ctx context.Context, _ securityspy.Event) {
+		<-ctx.Done()
+		ctxErr <- ctx.Err()
+	}, nil)
+
+	sspy.Events.Custom(3, "stuck")
+	require.Eventually(t, func() bool { return sspy.Events.BindingStats()[0].Delivered == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, sspy.Events.StopDrain(ctx, true), context.DeadlineExceeded)
+	require.ErrorIs(t, <-ctxErr, context.Canceled, "callbacks are canceled when the drain gives up")
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -26,7 +27,7 @@ func (c *Cameras) All() []*Camera {
 		return nil
 	}
 
-	return c.cameras
+	return slices.Clone(c.cameras)
 }
 
 // ByNum returns an interface for a single camera.
@@ -227,6 +228,13 @@ func (c *Camera) GetJPEG(ops *VidOps) (image.Image, error) {
 	return jpgImage, nil
 }
 
+// GetJPEGBytes returns an image from a camera as the JPEG bytes the server sent.
+// VidOps defines the image size. ops.FPS is ignored.
+// Makes several attempts in case of an error or time out.
+func (c *Camera) GetJPEGBytes(ops *VidOps) ([]byte, error) {
+	return c.fetchJPEGBytes(ops)
+}
+
 // SaveJPEG gets a picture from a camera and puts it in a file (path).
 // Fails if the path already exists. VidOps defines the image size; ops.FPS is ignored.
 // Writes the server JPEG bytes directly (no decode/re-encode).
@@ -345,15 +353,21 @@ func (c *Camera) fetchJPEGBytesOnce(ops *VidOps, client *http.Client) ([]byte, e
 //
 // ToggleMotion and ToggleActions use similar ++ssControl* endpoints and work on
 // tested SecuritySpy v5 servers. Continuous control is often unavailable (HTTP 404);
-// in that case this method returns ErrUnsupported. Use SetSchedule with
+// in that case this method returns ErrUnsupported, and keeps returning it without
+// a request until the server version changes (see Capabilities). Use SetSchedule with
 // CameraModeContinuous to arm or disarm continuous capture via the schedule API instead.
 func (c *Camera) ToggleContinuous(arm CameraArmMode) error {
+	if !c.server.Capabilities().ContinuousControl {
+		return c.server.unsupported(continuousControlPath)
+	}
+
 	params := make(url.Values)
 	params.Set("arm", string(arm))
 
-	if err := c.server.SimpleReq("++ssControlContinuous", params, c.Number); err != nil {
+	if err := c.server.SimpleReq(continuousControlPath, params, c.Number); err != nil {
 		if errors.Is(err, server.ErrNotFound) {
-			return ErrUnsupported
+			c.server.setMissing(continuousControlPath)
+			return fmt.Errorf("%w: %w", ErrUnsupported, err)
 		}
 
 		return fmt.Errorf("request failed: %w", err)
@@ -427,7 +441,7 @@ func (c *Camera) HLSURL() string {
 }
 
 // HLSMediaPlaylistURL returns the fixed-quality HLS media playlist URL (v6+).
-// quality: 0 low, 1 medium, 2 high.
+// quality: 0 low, 1 medium, 2 high. Check Capabilities().HLSMediaPlaylist before using it.
 func (c *Camera) HLSMediaPlaylistURL(quality int) string {
 	params := c.makeRequestParams(nil)
 	params.Set("quality", strconv.Itoa(quality))
@@ -450,6 +464,7 @@ func (c *Camera) LiveURL() string {
 }
 
 // MultiplexURL builds an authenticated ++multiplex grid URL (v6+).
+// Check Capabilities().Multiplex before using it.
 func (s *Server) MultiplexURL(ops *MultiplexOps) string {
 	params := make(url.Values)
 
@@ -4,12 +4,15 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+
+	"golift.io/securityspy/v2/server"
 )
 
 // ErrUnsupported is returned when the SecuritySpy server does not implement an API endpoint.
 // ToggleContinuous returns this on many builds where ++ssControlContinuous is missing;
-// use SetSchedule with CameraModeContinuous instead.
-var ErrUnsupported = errors.New("securityspy endpoint unsupported")
+// use SetSchedule with CameraModeContinuous instead. This is server.ErrUnsupported,
+// so API errors classified as unsupported (HTTP 405/501) match it too.
+var ErrUnsupported = server.ErrUnsupported
 
 // ErrCameraModesStatus is returned when ++cameramodes returns a non-200 status.
 var ErrCameraModesStatus = errors.New("camera modes request failed")
@@ -115,7 +118,8 @@ func (bit *CameraSchedule) UnmarshalXML(d *xml.Decoder, start xml.StartElement)
 // Camera defines the data returned from the SecuritySpy API ++systemInfo method.
 // Exported field names stay stable across v5/v6; XML unmarshaling accepts both schemas.
 type Camera struct {
-	server *Server
+	server     *Server
+	generation uint64
 
 	Number              int
 	Connected           YesNoBool
@@ -0,0 +1,75 @@
+package securityspy
+
+import "fmt"
+
+// Capabilities lists the endpoints and behaviors a server supports. Most come from the
+// server version. Others are probed: the first request to the endpoint decides, and
+// the result is cached until the server version changes. Before the first Refresh the
+// version is unknown and everything is assumed to be supported.
+type Capabilities struct {
+	Version Version // Zero until the first Refresh.
+	// Settings is true if the ++settings-* endpoints exist (v6+).
+	Settings bool
+	// Multiplex is true if ++multiplex grid pages exist (v6+). See Server.MultiplexURL.
+	Multiplex bool
+	// HLSMediaPlaylist is true if fixed-quality ++hls_mediaplaylist exists (v6+). See Camera.HLSMediaPlaylistURL.
+	HLSMediaPlaylist bool
+	// Groups is true if ++systemInfo includes camera groups (v6+).
+	Groups bool
+	// TriggerReasonsV6 is true if trigger reason bit 512 means HomeKit and 1024 means Animal (v6+).
+	// On v5 bit 512 means Animal.
+	TriggerReasonsV6 bool
+	// ContinuousControl is true unless ++ssControlContinuous returned 404 on this server version.
+	ContinuousControl bool
+}
+
+// Endpoints probed by Capabilities.
+const continuousControlPath = "++ssControlContinuous"
+
+// Capabilities returns what the server supports, based on the version from the last Refresh
+// and on endpoints that already returned 404.
+func (s *Server) Capabilities() Capabilities {
+	version := s.Snapshot().Info.ParsedVersion()
+	v6 := version.IsZero() || version.AtLeast(6, 0) //nolint:mnd
+
+	return Capabilities{
+		Version:           version,
+		Settings:          v6,
+		Multiplex:         v6,
+		HLSMediaPlaylist:  v6,
+		Groups:            v6,
+		TriggerReasonsV6:  v6,
+		ContinuousControl: !s.missing(continuousControlPath),
+	}
+}
+
+// missing returns true if an endpoint returned 404 on the current server version.
+func (s *Server) missing(apiPath string) bool {
+	s.capsMu.Lock()
+	defer s.capsMu.Unlock()
+
+	version, ok := s.notFound[apiPath]
+
+	return ok && version == s.Snapshot().Info.Version
+}
+
+// setMissing caches that an endpoint returned 404 on the current server version.
+func (s *Server) setMissing(apiPath string) {
+	s.capsMu.Lock()
+	defer s.capsMu.Unlock()
+
+	if s.notFound == nil {
+		s.notFound = make(map[string]string)
+	}
+
+	s.notFound[apiPath] = s.Snapshot().Info.Version
+}
+
+// unsupported returns an ErrUnsupported error for an endpoint the server cannot handle.
+func (s *Server) unsupported(apiPath string) error {
+	if version := s.Snapshot().Info.Version; version != "" {
+		return fmt.Errorf("%w: %s on SecuritySpy %s", ErrUnsupported, apiPath, version)
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnsupported, apiPath)
+}
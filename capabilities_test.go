@@ -0,0 +1,90 @@
+package securityspy_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+
+	for text, want := range map[string]securityspy.Version{
+		"6.20":  {Major: 6, Minor: 20, Raw: "6.20"},
+		"5.5.4": {Major: 5, Minor: 5, Patch: 4, Raw: "5.5.4"},
+		"6.1b2": {Major: 6, Minor: 1, Raw: "6.1b2"},
+		"6":     {Major: 6, Raw: "6"},
+	} {
+		version, err := securityspy.ParseVersion(text)
+		require.NoError(t, err, text)
+		require.Equal(t, want, version, text)
+	}
+
+	_, err := securityspy.ParseVersion("beta")
+	require.ErrorIs(t, err, securityspy.ErrVersionParse)
+
+	v620, _ := securityspy.ParseVersion("6.20")
+	v63, _ := securityspy.ParseVersion("6.3")
+	require.Equal(t, 1, v620.Compare(v63), "minor versions are numbers")
+	require.True(t, v620.AtLeast(6, 0))
+	require.False(t, v63.AtLeast(6, 4))
+	require.Equal(t, "6.20", v620.String())
+}
+
+func TestCapabilitiesByVersion(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy := securityspy.NewMust(fake.Config())
+	require.True(t, sspy.Capabilities().Settings, "unknown versions are assumed to support everything")
+
+	fake.SetInfo(func(info *securityspytest.ServerInfo) { info.Version = "5.5.4" })
+	require.NoError(t, sspy.Refresh())
+
+	caps := sspy.Capabilities()
+	require.Equal(t, 5, caps.Version.Major)
+	require.False(t, caps.Settings)
+	require.False(t, caps.Multiplex)
+	require.False(t, caps.TriggerReasonsV6)
+	require.True(t, caps.ContinuousControl)
+
+	_, err := sspy.GetGeneralSettings()
+	require.ErrorIs(t, err, securityspy.ErrUnsupported)
+	require.ErrorIs(t, sspy.SetCameraSettings(map[string][]string{"cameraNum": {"3"}}), securityspy.ErrUnsupported)
+	require.Zero(t, fake.CountRequests("/++settings-general"), "unsupported requests are never sent")
+
+	fake.SetInfo(func(info *securityspytest.ServerInfo) { info.Version = "6.20" })
+	require.NoError(t, sspy.Refresh())
+
+	_, err = sspy.GetGeneralSettings()
+	require.NoError(t, err)
+}
+
+func TestCapabilitiesContinuousProbe(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	fake.Handle("/++ssControlContinuous", http.NotFound)
+
+	door := sspy.Snapshot().Cameras.ByNum(3)
+	require.ErrorIs(t, door.ToggleContinuous(securityspy.CameraArm), securityspy.ErrUnsupported)
+	require.False(t, sspy.Capabilities().ContinuousControl)
+	require.ErrorIs(t, door.ToggleContinuous(securityspy.CameraArm), securityspy.ErrUnsupported)
+	require.Equal(t, 1, fake.CountRequests("/++ssControlContinuous"), "the 404 is cached")
+
+	fake.Handle("/++ssControlContinuous", nil)
+	fake.SetInfo(func(info *securityspytest.ServerInfo) { info.Version = "6.21" })
+	require.NoError(t, sspy.Refresh())
+	require.True(t, sspy.Capabilities().ContinuousControl, "a new version is probed again")
+	require.NoError(t, sspy.Snapshot().Cameras.ByNum(3).ToggleContinuous(securityspy.CameraArm))
+}
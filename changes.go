@@ -0,0 +1,178 @@
+package securityspy
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Diff returns every inventory difference between this snapshot and a newer one.
+// Cameras are matched by number and name first, then by name alone (renumbered),
+// then by number alone (renamed). Cameras left over were added or removed.
+// Only the two snapshots are read, never the Server's deprecated inventory fields.
+func (s *Snapshot) Diff(next *Snapshot) []Change {
+	changes := diffServer(s.Info, next.Info)
+
+	var (
+		oldCams = slices.Clone(s.Cameras.All())
+		newCams = slices.Clone(next.Cameras.All())
+		pairs   [][2]*Camera
+	)
+
+	for _, match := range []func(a, b *Camera) bool{
+		func(a, b *Camera) bool { return a.Number == b.Number && a.Name == b.Name },
+		func(a, b *Camera) bool { return a.Name == b.Name },
+		func(a, b *Camera) bool { return a.Number == b.Number },
+	} {
+		oldCams = slices.DeleteFunc(oldCams, func(prev *Camera) bool {
+			idx := slices.IndexFunc(newCams, func(cam *Camera) bool { return match(prev, cam) })
+			if idx < 0 {
+				return false
+			}
+
+			pairs = append(pairs, [2]*Camera{prev, newCams[idx]})
+			newCams = slices.Delete(newCams, idx, idx+1)
+
+			return true
+		})
+	}
+
+	slices.SortFunc(pairs, func(a, b [2]*Camera) int { return a[1].Number - b[1].Number })
+
+	for _, pair := range pairs {
+		changes = append(changes, s.diffCamera(next, pair[0], pair[1])...)
+	}
+
+	for _, cam := range oldCams {
+		changes = append(changes, Change{Type: EventCameraRemoved, Camera: cam, Previous: cam, Field: "Name", Old: cam.Name})
+	}
+
+	for _, cam := range newCams {
+		changes = append(changes, Change{Type: EventCameraAdded, Camera: cam, Field: "Name", New: cam.Name})
+	}
+
+	return changes
+}
+
+// diffServer compares the server fields worth announcing.
+func diffServer(prev, info *ServerInfo) []Change {
+	var changes []Change
+
+	for _, field := range []struct {
+		name  string
+		value func(*ServerInfo) string
+	}{
+		{"Name", func(i *ServerInfo) string { return i.Name }},
+		{"ServerName", func(i *ServerInfo) string { return i.ServerName }},
+		{"UUID", func(i *ServerInfo) string { return i.UUID }},
+		{"Version", func(i *ServerInfo) string { return i.Version }},
+		{"NewVersion", func(i *ServerInfo) string { return i.NewVersion }},
+		{"CertExpiryTime", func(i *ServerInfo) string { return formatTime(i.CertExpiryTime) }},
+		{"HTTPSEnabled", func(i *ServerInfo) string { return strconv.FormatBool(i.HTTPSEnabled.Val) }},
+		{"HTTPPort", func(i *ServerInfo) string { return strconv.Itoa(i.HTTPPort) }},
+		{"HTTPSPort", func(i *ServerInfo) string { return strconv.Itoa(i.HTTPSPort) }},
+	} {
+		if oldVal, newVal := field.value(prev), field.value(info); oldVal != newVal {
+			changes = append(changes, Change{Type: EventServerChange, Field: field.name, Old: oldVal, New: newVal})
+		}
+	}
+
+	return changes
+}
+
+// diffCamera compares one camera across two snapshots.
+func (s *Snapshot) diffCamera(next *Snapshot, prev, cam *Camera) []Change {
+	var changes []Change
+
+	add := func(eventType EventType, field, oldVal, newVal string) {
+		if oldVal != newVal {
+			changes = append(changes, Change{
+				Type: eventType, Camera: cam, Previous: prev, Field: field, Old: oldVal, New: newVal,
+			})
+		}
+	}
+
+	add(EventCameraRenumbered, "Number", strconv.Itoa(prev.Number), strconv.Itoa(cam.Number))
+	add(EventCameraRenamed, "Name", prev.Name, cam.Name)
+	add(EventCameraConnection, "Connected", strconv.FormatBool(prev.Connected.Val), strconv.FormatBool(cam.Connected.Val))
+	add(EventCameraModeChange, "ModeC", strconv.FormatBool(prev.ModeC.Val), strconv.FormatBool(cam.ModeC.Val))
+	add(EventCameraModeChange, "ModeM", strconv.FormatBool(prev.ModeM.Val), strconv.FormatBool(cam.ModeM.Val))
+	add(EventCameraModeChange, "ModeA", strconv.FormatBool(prev.ModeA.Val), strconv.FormatBool(cam.ModeA.Val))
+	add(EventCameraSchedule, "ScheduleIDCC", prev.ScheduleIDCC.String(), cam.ScheduleIDCC.String())
+	add(EventCameraSchedule, "ScheduleIDMC", prev.ScheduleIDMC.String(), cam.ScheduleIDMC.String())
+	add(EventCameraSchedule, "ScheduleIDA", prev.ScheduleIDA.String(), cam.ScheduleIDA.String())
+	add(EventCameraSchedule, "ScheduleOverrideCC", prev.ScheduleOverrideCC.String(), cam.ScheduleOverrideCC.String())
+	add(EventCameraSchedule, "ScheduleOverrideMC", prev.ScheduleOverrideMC.String(), cam.ScheduleOverrideMC.String())
+	add(EventCameraSchedule, "ScheduleOverrideA", prev.ScheduleOverrideA.String(), cam.ScheduleOverrideA.String())
+	add(EventCameraGroupChange, "Groups", s.groupNames(prev.Number), next.groupNames(cam.Number))
+
+	return changes
+}
+
+// groupNames returns the sorted, comma-separated names of the groups a camera is in.
+func (s *Snapshot) groupNames(cameraNum int) string {
+	var names []string
+
+	for _, group := range s.Groups {
+		if slices.Contains(group.CameraNumbers(), cameraNum) {
+			names = append(names, group.Name)
+		}
+	}
+
+	slices.Sort(names)
+
+	return strings.Join(names, ",")
+}
+
+// String returns the schedule name, or its ID if it has no name.
+func (c CameraSchedule) String() string {
+	if c.Name != "" {
+		return c.Name
+	}
+
+	return strconv.Itoa(c.ID)
+}
+
+// String describes the change, ie. "Camera Mode Changed: Porch ModeM false -> true".
+func (c Change) String() string {
+	switch c.Type { //nolint:exhaustive // only the camera life cycle is special.
+	case EventCameraAdded:
+		return EventName(c.Type) + ": " + c.New
+	case EventCameraRemoved:
+		return EventName(c.Type) + ": " + c.Old
+	}
+
+	prefix := EventName(c.Type) + ": "
+	if c.Camera != nil {
+		prefix += c.Camera.Name + " "
+	}
+
+	return prefix + c.Field + " " + c.Old + " -> " + c.New
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// publishChanges sends each change to the Events bindings.
+func (e *Events) publishChanges(changes []Change) {
+	now := time.Now().Round(time.Second)
+
+	for idx := range changes {
+		change := &changes[idx]
+		e.enqueue(&Event{
+			Time:   now,
+			When:   now,
+			ID:     -9996, //nolint:mnd // library events use fixed negative IDs.
+			Camera: change.Camera,
+			Type:   change.Type,
+			Msg:    change.String(),
+			Change: change,
+		})
+	}
+}
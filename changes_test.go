@@ -0,0 +1,79 @@
+package securityspy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+func TestRefreshChanges(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+	require.Empty(t, sspy.Snapshot().Changes, "the first refresh is not a change")
+
+	fake.SetInfo(func(info *securityspytest.ServerInfo) { info.Version = "6.21" })
+	fake.RemoveCamera(2)
+	fake.AddCamera(securityspytest.Camera{Number: 7, Name: "Porch", ScheduleMC: 1, ScheduleA: 1})
+	fake.AddCamera(securityspytest.Camera{Number: 9, Name: "Yard"})
+	fake.UpdateCamera(3, func(cam *securityspytest.Camera) {
+		cam.Name = "Front Door"
+		cam.Connected = false
+		cam.ModeC = true
+		cam.OverrideMC = 2
+	})
+	fake.SetGroups(securityspytest.Group{Number: 0, Name: "Base", Cameras: []int{7}})
+	require.NoError(t, sspy.Refresh())
+
+	changes := map[string]securityspy.Change{}
+	for _, change := range sspy.Snapshot().Changes {
+		changes[string(change.Type)+" "+change.Field+" "+change.New] = change
+	}
+
+	require.Len(t, changes, 8, changes)
+	require.Equal(t, "6.20", changes["SERVER_CHANGE Version 6.21"].Old)
+	require.Equal(t, "2", changes["CAMERA_RENUMBERED Number 7"].Old, "matched by name")
+	require.Equal(t, "Door", changes["CAMERA_RENAMED Name Front Door"].Old, "matched by number")
+	require.Equal(t, 3, changes["CAMERA_CONNECTION Connected false"].Previous.Number)
+	require.Contains(t, changes, "CAMERA_MODE ModeC true")
+	require.Equal(t, "No Override", changes["CAMERA_SCHEDULE ScheduleOverrideMC Armed Until Schedule Event"].Old)
+	require.Equal(t, "Base", changes["CAMERA_GROUPS Groups "].Old)
+	require.Equal(t, 9, changes["CAMERA_ADDED Name Yard"].Camera.Number)
+}
+
+func TestRefreshChangeEvents(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	events := make(chan securityspy.Event, 10)
+	sspy.Events.BindChan(securityspy.EventCameraRemoved, events)
+	sspy.Events.Watch(10*time.Millisecond, true)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	require.Eventually(t, func() bool { return fake.EventStreamClients() == 1 }, time.Second, time.Millisecond)
+
+	fake.RemoveCamera(2)
+	fake.PushEvent(-1, "CONFIGCHANGE")
+
+	select {
+	case event := <-events:
+		require.Equal(t, securityspy.EventCameraRemoved, event.Type)
+		require.Equal(t, "Porch", event.Camera.Name)
+		require.Equal(t, "Porch", event.Change.Old)
+		require.Equal(t, "Camera Removed: Porch", event.Msg)
+	case <-time.After(time.Second):
+		t.Fatal("no camera removed event")
+	}
+}
@@ -3,14 +3,19 @@ package securityspy
 import (
 	"bufio"
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"golift.io/securityspy/v2/server"
 )
 
 // String provides a description of an event.
@@ -27,48 +32,39 @@ func (e *Event) String() string {
 
 // BindFunc binds a call-back function to an Event in SecuritySpy.
 // Use this to receive incoming events via a callback method in a go routine.
+// Each event gets its own go routine, so events may arrive out of order.
+// Events are dropped if DefaultBindQueue events are waiting for one of the binding's
+// callback workers. Use BindFuncConfig to pick another DeliveryPolicy or DeliveryOrder.
 func (e *Events) BindFunc(event EventType, callBack func(Event)) {
-	if callBack == nil {
-		return
-	}
-
-	e.binds.Lock()
-	defer e.binds.Unlock()
-
-	if val, ok := e.eventBinds[event]; ok {
-		e.eventBinds[event] = append(val, callBack)
-
-		return
-	}
-
-	e.eventBinds[event] = []func(Event){callBack}
+	e.BindFuncConfig(event, callBack, nil)
 }
 
 // BindChan binds a receiving channel to an Event in SecuritySpy.
 // Use this to receive incoming events over a channel.
-// Avoid using unbuffered channels as they may block further event processing.
+// Events are queued while the channel is full, and dropped once DefaultBindQueue
+// events are waiting. Use BindChanConfig to pick another DeliveryPolicy.
 func (e *Events) BindChan(event EventType, channel chan Event) {
-	if channel == nil {
-		return
-	}
-
-	e.chans.Lock()
-	defer e.chans.Unlock()
-
-	if val, ok := e.eventChans[event]; ok {
-		e.eventChans[event] = append(val, channel)
-
-		return
-	}
-
-	e.eventChans[event] = []chan Event{channel}
+	e.BindChanConfig(event, channel, nil)
 }
 
 // Stop stops Watch() loops and disconnects from the event stream.
-// No further callback messages will fire after this is called.
-// Closes all channels that were passed to BindChan if closeChans=true.
+// No further callback messages will fire after this is called, but callbacks
+// that are already running are not waited for; use StopDrain for that.
+// The contexts passed to BindFuncContext callbacks are canceled.
+// Closes all channels that were passed to BindChan or returned by SubscribeState if closeChans=true.
+// Closed channels are unbound and events still queued for them are discarded.
 // Stop writing to the channels with Custom() before calling Stop().
 func (e *Events) Stop(closeChans bool) {
+	e.stopWatch()
+	e.cancelCallbacks()
+
+	if closeChans {
+		e.closeChans()
+	}
+}
+
+// stopWatch stops the Watch() go routines and waits for them to return.
+func (e *Events) stopWatch() {
 	e.mu.Lock()
 	running := e.Running
 	cancel := e.cancel
@@ -79,74 +75,62 @@ func (e *Events) Stop(closeChans bool) {
 	e.stream = nil
 	e.mu.Unlock()
 
-	if running {
-		if cancel != nil {
-			cancel()
-		}
-
-		if stream != nil {
-			_ = stream.Close()
-		}
+	if !running {
+		return
+	}
 
-		e.wg.Wait()
+	if cancel != nil {
+		cancel()
 	}
 
-	if !closeChans {
-		return
+	if stream != nil {
+		_ = stream.Close()
 	}
 
-	e.chans.Lock()
-	defer e.chans.Unlock()
+	e.wg.Wait()
+}
 
-	closed := make(map[chan Event]struct{})
+// closeChans closes and unbinds every bound channel, and closes state subscriptions.
+func (e *Events) closeChans() {
+	e.closeStateSubs()
 
-	for _, chans := range e.eventChans {
-		for idx := range chans {
-			if _, ok := closed[chans[idx]]; ok {
-				continue
-			}
+	closed := make(map[chan Event]struct{})
 
-			close(chans[idx])
-			closed[chans[idx]] = struct{}{}
+	for _, binding := range e.unbind((*Binding).isChan) {
+		if _, ok := closed[binding.channel]; ok || binding.sub {
+			continue
 		}
+
+		close(binding.channel)
+		closed[binding.channel] = struct{}{}
 	}
 }
 
-// UnbindAll removes all event bindings and channels.
+// UnbindAll removes all event bindings and channels, and closes every channel from Subscribe.
 func (e *Events) UnbindAll() {
-	e.binds.Lock()
-	e.chans.Lock()
-
-	defer func() {
-		e.binds.Unlock()
-		e.chans.Unlock()
-	}()
-
-	e.eventBinds = make(map[EventType][]func(Event))
-	e.eventChans = make(map[EventType][]chan Event)
+	e.unbind(func(*Binding) bool { return true })
 }
 
-// UnbindChan removes all bound channels for a particular event.
+// UnbindChan removes all bound channels for a particular event. Subscriptions are not removed.
 func (e *Events) UnbindChan(event EventType) {
-	e.chans.Lock()
-	defer e.chans.Unlock()
-
-	delete(e.eventChans, event)
+	e.unbind(func(binding *Binding) bool { return binding.event == event && binding.isChan() && !binding.sub })
 }
 
 // UnbindFunc removes all bound callbacks for a particular event.
-// EventType is a set of constants that begin with Event*.
+// EventType is a set of constants that begin with Event*. Callbacks that are
+// running are not waited for, so a callback may unbind its own event type.
 func (e *Events) UnbindFunc(event EventType) {
-	e.binds.Lock()
-	defer e.binds.Unlock()
-
-	delete(e.eventBinds, event)
+	e.unbind(func(binding *Binding) bool { return binding.event == event && binding.isFunc() })
 }
 
 // Watch kicks off the routines to watch the eventStream and fire callback bindings.
 // If your application relies on event stream messages, call this at least once
 // to connect the stream. If you have no call back functions or channels then do not
 // call this. Call Stop() to close the connection when you're done with it.
+// The watcher reconnects when the stream fails. It waits retryInterval after the
+// first failure and doubles the wait after each failed dial, up to MaxRetryInterval.
+// Use State, Stats, WaitConnected and SubscribeState to follow the connection.
+// With refreshOnConfigChange, CONFIGCHANGE events call Poller.Refresh.
 func (e *Events) Watch(retryInterval time.Duration, refreshOnConfigChange bool) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -159,14 +143,13 @@ func (e *Events) Watch(retryInterval time.Duration, refreshOnConfigChange bool)
 	watchCtx := e.ctx
 	e.eventChan = make(chan *Event, EventBuffer)
 
-	if e.callbackSem == nil {
-		e.callbackSem = make(chan struct{}, maxCallbackWorkers)
-	}
-
 	e.Running = true
+	maxDelay := cmp.Or(e.MaxRetryInterval, DefaultMaxRetryInterval)
+	keepAlive := cmp.Or(e.KeepAliveTimeout, DefaultKeepAliveTimeout)
 
+	e.setState(StreamConnecting, nil)
 	e.wg.Go(func() { e.eventStreamSelector(watchCtx, refreshOnConfigChange) })
-	e.wg.Go(func() { e.eventStreamScanner(watchCtx, retryInterval) })
+	e.wg.Go(func() { e.eventStreamScanner(watchCtx, retryInterval, maxDelay, keepAlive) })
 }
 
 // Custom fires an event into the running event Watcher. Any functions or
@@ -179,77 +162,109 @@ func (e *Events) Custom(cameraNum int, msg string) {
 func (e *Events) custom(eventType EventType, eventID, cam int, msg string) {
 	now := time.Now().Round(time.Second)
 
-	var camera *Camera
-	if e.server.Cameras != nil {
-		camera = e.server.Cameras.ByNum(cam)
-	}
-
 	e.enqueue(&Event{
 		Time:   now,
 		When:   now,
 		ID:     eventID,
 		Msg:    string(eventType) + " " + msg,
 		Type:   eventType,
-		Camera: camera,
+		Camera: e.server.Snapshot().Cameras.ByNum(cam),
 	})
 }
 
 /* INTERFACE HELPER METHODS FOLLOW */
 
 // eventStreamScanner connects to the securityspy event stream and fires events into a channel.
-// EventStreamDisconnect is only emitted after a live stream ends, not on failed dials.
-//
-//nolint:cyclop // but it runs forever!
-func (e *Events) eventStreamScanner(ctx context.Context, retryInterval time.Duration) {
-	for {
+// EventStreamDisconnect is only emitted after a live stream ends, not on failed dials;
+// those are reported with a state change to StreamBackoff. The delay before each
+// reconnect starts at retryInterval and doubles with each failed dial, up to maxDelay.
+func (e *Events) eventStreamScanner(ctx context.Context, retryInterval, maxDelay, keepAlive time.Duration) {
+	defer e.setState(StreamStopped, nil)
+
+	policy := server.RetryPolicy{
+		BaseDelay: server.Duration{Duration: retryInterval},
+		MaxDelay:  server.Duration{Duration: max(retryInterval, maxDelay)},
+	}
+
+	for failures := 0; ; {
 		if ctx.Err() != nil {
 			return
 		}
 
+		e.setState(StreamConnecting, nil)
+
 		stream, err := e.eventStreamConnect(ctx)
 		if err != nil {
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(retryInterval):
-				continue
-			}
+			failures++
+		} else {
+			failures = 0
+			err = e.eventStreamRead(ctx, stream, keepAlive)
+		}
+
+		if ctx.Err() != nil || !e.backoff(ctx, policy.Delay(max(failures, 1)), err, failures > 0) {
+			return
 		}
+	}
+}
 
-		scanner := bufio.NewScanner(stream)
-		scanner.Split(scanLinesCR)
+// eventStreamRead reads events from a connected stream until it closes, then emits EventStreamDisconnect.
+// If no line arrives for keepAlive, a watchdog closes the stream. A negative keepAlive disables it.
+func (e *Events) eventStreamRead(ctx context.Context, stream io.ReadCloser, keepAlive time.Duration) error {
+	var (
+		stalled  atomic.Bool
+		watchdog *time.Timer
+	)
 
-		for scanner.Scan() {
-			// Constantly scan for new events, then report them to the event channel.
-			if text := scanner.Text(); strings.Count(text, " ") > 2 { //nolint:mnd // we need at least 2.
-				e.enqueue(e.UnmarshalEvent(text))
-			}
+	if keepAlive > 0 {
+		watchdog = time.AfterFunc(keepAlive, func() {
+			stalled.Store(true)
+			_ = stream.Close() // Unblocks the scanner.
+		})
+		defer watchdog.Stop()
+	}
 
-			if ctx.Err() != nil {
-				break
-			}
+	scanner := bufio.NewScanner(stream)
+	scanner.Split(scanLinesCR)
+
+	for scanner.Scan() {
+		if watchdog != nil {
+			watchdog.Reset(keepAlive)
 		}
 
-		err = scanner.Err()
-		_ = stream.Close()
-		e.clearStream(stream)
+		// Constantly scan for new events, then report them to the event channel.
+		if text := scanner.Text(); strings.Count(text, " ") > 2 { //nolint:mnd // we need at least 2.
+			e.received()
 
-		if ctx.Err() != nil {
-			return
+			if event := e.UnmarshalEvent(text); e.checkSequence(event) {
+				e.enqueue(event)
+			}
 		}
 
-		msg := "Connection Closed"
-		if err != nil && !errors.Is(err, ErrDisconnect) {
-			msg = err.Error()
+		if ctx.Err() != nil {
+			break
 		}
+	}
 
-		e.custom(EventStreamDisconnect, -10000, -1, msg)
+	err := scanner.Err()
+	_ = stream.Close()
+	e.clearStream(stream)
 
-		select {
-		case <-ctx.Done():
-			return
-		case <-time.After(retryInterval):
-		}
+	switch {
+	case ctx.Err() != nil:
+		return nil
+	case stalled.Load():
+		err = fmt.Errorf("%w: nothing received in %v", ErrStreamStalled, keepAlive)
+		e.custom(EventStreamDisconnect, -10000, -1, err.Error())
+
+		return err
+	case err == nil || errors.Is(err, ErrDisconnect):
+		e.custom(EventStreamDisconnect, -10000, -1, "Connection Closed")
+
+		return fmt.Errorf("reading event stream: %w", ErrDisconnect)
+	default:
+		e.custom(EventStreamDisconnect, -10000, -1, err.Error())
+
+		return fmt.Errorf("reading event stream: %w", err)
 	}
 }
 
@@ -263,21 +278,28 @@ func (e *Events) eventStreamConnect(ctx context.Context) (io.ReadCloser, error)
 		return nil, fmt.Errorf("connecting event stream: %w", err)
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("connecting event stream: %w", e.server.ResponseError(resp, "++eventStream"))
+	}
+
 	e.mu.Lock()
 	e.stream = resp.Body
 	e.mu.Unlock()
 
+	e.setState(StreamConnected, nil)
 	e.custom(EventStreamConnect, -9999, -1, EventName(EventStreamConnect))
 
 	return resp.Body, nil
 }
 
-// eventStreamSelector watches the event channel.
-// Fires bound event call back functions.
-// Also reconnects to the event stream if the connection fails.
+// eventStreamSelector watches the event channel and queues each event on its bindings.
+// It also emits EventDeliveryDropped when bindings drop events.
 // There is a "loop" that occurs among the eventStream* methods.
 // Stop() properly handles the shutdown of the loop, so if can be safely restarted w/ Watch().
-func (e *Events) eventStreamSelector(ctx context.Context, refreshOnConfigChange bool) { //nolint:cyclop // oh well?
+func (e *Events) eventStreamSelector(ctx context.Context, refreshOnConfigChange bool) {
+	ticker := time.NewTicker(DropReportInterval)
+	defer ticker.Stop()
+
 	for {
 		var (
 			event *Event
@@ -287,6 +309,9 @@ func (e *Events) eventStreamSelector(ctx context.Context, refreshOnConfigChange
 		select {
 		case <-ctx.Done():
 			return
+		case <-ticker.C:
+			e.reportDrops(ctx)
+			continue
 		case event, ok = <-e.eventChan:
 			if !ok {
 				return
@@ -294,55 +319,23 @@ func (e *Events) eventStreamSelector(ctx context.Context, refreshOnConfigChange
 		}
 
 		if event.Type == EventConfigChange && refreshOnConfigChange {
-			e.serverRefresh(ctx)
+			_ = e.server.Poller.Refresh(ctx)
 		}
 
-		for _, callback := range e.callbacksFor(event.Type) {
-			if callback == nil {
-				continue
-			}
-
-			callbackFn := callback
-
-			select {
-			case e.callbackSem <- struct{}{}:
-				go func() {
-					defer func() { <-e.callbackSem }()
-
-					callbackFn(*event)
-				}()
-			default:
-			}
-		}
-
-		for _, ch := range e.channelsFor(event.Type) {
-			select {
-			case ch <- *event:
-			default:
-			}
-		}
+		e.dispatch(ctx, event)
 	}
 }
 
-func (e *Events) serverRefresh(ctx context.Context) {
-	if err := e.server.RefreshContext(ctx); err != nil {
-		e.custom(EventWatcherRefreshFail, -9997, -1, err.Error())
-
-		return
-	}
-
-	e.custom(EventWatcherRefreshed, -9998, -1, EventName(EventWatcherRefreshed))
-}
-
 /* 	Example Event Stream Flow:
 (new, v5)
 20190927092026 3 3 CLASSIFY HUMAN 99
 20190927092026 4 3 TRIGGER_M 9
 20190927092036 5 3 CLASSIFY HUMAN 5 VEHICLE 95
-20190927092040 5 X NULL
-20190927092050 6 3 FILE /Volumes/VolName/Cam/2019-07-26/26-07-2019 15-52-00 C Cam.m4v
-20190927092055 7 3 DISARM_M
-20190927092056 8 3 OFFLINE
+20190927092040 6 X NULL
+20190927092050 7 3 FILE /Volumes/VolName/Cam/2019-07-26/26-07-2019 15-52-00 C Cam.m4v
+20190927092055 8 3 DISARM_M
+20190927092056 9 3 OFFLINE
+Every event, including NULL, takes the next event number. See checkSequence.
 */
 
 // UnmarshalEvent turns raw text into an Event that can fire callbacks.
@@ -361,6 +354,7 @@ func (e *Events) UnmarshalEvent(text string) *Event {
 		newEvent = &Event{Msg: text, ID: -1, Time: time.Now()}
 		// Parse the time stamp; append the Offset from ++systemInfo to get the right time-location.
 		eventTime string
+		cameraNum = -1
 	)
 
 	if len(parts) < 4 {
@@ -372,12 +366,8 @@ func (e *Events) UnmarshalEvent(text string) *Event {
 
 	newEvent.Msg = parts[3]
 
-	gmtOffset := 0.0
-	if e.server.Info != nil {
-		gmtOffset = e.server.Info.GmtOffset.Hours()
-	}
-
-	eventTime = fmt.Sprintf("%v%+03.0f", parts[0], gmtOffset)
+	snap := e.server.Snapshot()
+	eventTime = fmt.Sprintf("%v%+03.0f", parts[0], snap.Info.GmtOffset.Hours())
 
 	//nolint:gosmopolitan // The event stream uses the system's local time.
 	if newEvent.When, err = time.ParseInLocation(EventTimeFormat+"-07", eventTime, time.Local); err != nil {
@@ -393,10 +383,10 @@ func (e *Events) UnmarshalEvent(text string) *Event {
 
 	// Parse the camera number.
 	parts[2] = strings.TrimPrefix(parts[2], "CAM")
-	if parts[2] != "X" && e.server.Cameras != nil {
-		if cameraNum, err := strconv.Atoi(parts[2]); err != nil {
+	if parts[2] != "X" {
+		if cameraNum, err = strconv.Atoi(parts[2]); err != nil {
 			newEvent.Errors = append(newEvent.Errors, ErrCAMParseFail)
-		} else if newEvent.Camera = e.server.Cameras.ByNum(cameraNum); newEvent.Camera == nil {
+		} else if newEvent.Camera = snap.Cameras.ByNum(cameraNum); newEvent.Camera == nil {
 			newEvent.Errors = append(newEvent.Errors, ErrCAMMissing)
 		}
 	}
@@ -411,52 +401,94 @@ func (e *Events) UnmarshalEvent(text string) *Event {
 		newEvent.Type = EventUnknownEvent
 	}
 
-	if newEvent.Type == EventClassify && len(parts) > 1 {
-		parseClassifyFields(parts[1:], newEvent)
-	}
+	e.parsePayload(newEvent, parts, snap, cameraNum)
 
-	// If this is a trigger-type event, add the trigger reason(s)
-	if (newEvent.Type == EventTriggerAction || newEvent.Type == EventTriggerMotion) && len(parts) == 2 {
-		b, _ := strconv.Atoi(parts[1])
-		msg := ""
+	return newEvent
+}
 
-		// Check if this bitmask contains any of our known reasons.
-		for flag, txt := range Reasons() {
-			if b&int(flag) != 0 {
-				if msg != "" {
-					msg += ", "
-				}
+// SetReasonTable makes UnmarshalEvent decode trigger reasons with this table
+// instead of the one picked by server version. Pass nil to pick by version again.
+func (e *Events) SetReasonTable(table ReasonTable) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
-				newEvent.Reasons = append(newEvent.Reasons, flag)
-				msg += txt
-			}
-		}
+	e.reasons = table
+}
 
-		newEvent.Msg += " - Reasons: " + msg
-		if msg == "" {
-			newEvent.Msg += UnknownReasonText
-		}
+// decodeReasons fills in the trigger reasons from the event's bitmask, lowest bit first.
+func (e *Events) decodeReasons(event *Event, version Version) {
+	e.mu.RLock()
+	table := e.reasons
+	e.mu.RUnlock()
+
+	if table == nil {
+		table = ReasonTableFor(version)
 	}
 
-	return newEvent
-}
+	names := []string{}
 
-func parseClassifyFields(parts []string, event *Event) {
-	for idx := 0; idx+1 < len(parts); idx += 2 {
-		value, err := strconv.Atoi(parts[idx+1])
-		if err != nil {
+	for bit := 1; bit > 0 && bit <= event.ReasonMask; bit <<= 1 {
+		if event.ReasonMask&bit == 0 {
 			continue
 		}
 
-		switch parts[idx] {
-		case "HUMAN":
-			event.ClassifyHuman = value
-		case "VEHICLE":
-			event.ClassifyVehicle = value
-		case "ANIMAL":
-			event.ClassifyAnimal = value
+		if reason, ok := table[bit]; ok {
+			event.Reasons = append(event.Reasons, reason)
+			names = append(names, reason.String())
+		} else {
+			event.UnknownReasons |= bit
+			names = append(names, fmt.Sprintf("%s (%d)", UnknownReasonText, bit))
 		}
 	}
+
+	event.Msg += " - Reasons: " + strings.Join(names, ", ")
+	if len(names) == 0 {
+		event.Msg += UnknownReasonText
+	}
+}
+
+// checkSequence compares an event's number with the last one from the stream. It emits an
+// EventStreamGap when numbers are skipped or reset, and returns false for events the
+// server already sent, which it replays after a reconnect.
+func (e *Events) checkSequence(event *Event) bool {
+	if event.ID < 0 {
+		return true
+	}
+
+	last, lastWhen := e.lastID, e.lastWhen
+
+	switch {
+	case last == 0 || event.ID == last+1:
+	case event.ID > last:
+		e.gap(&EventGap{After: last, Next: event.ID, Missed: event.ID - last - 1})
+	case !event.When.After(lastWhen):
+		return false // Replayed.
+	default:
+		e.gap(&EventGap{After: last, Next: event.ID, Missed: -1, Reset: true})
+	}
+
+	e.lastID, e.lastWhen = event.ID, event.When
+
+	return true
+}
+
+// gap emits an EventStreamGap event.
+func (e *Events) gap(gap *EventGap) {
+	now := time.Now().Round(time.Second)
+	msg := fmt.Sprintf("missed %d events between %d and %d", gap.Missed, gap.After, gap.Next)
+
+	if gap.Reset {
+		msg = fmt.Sprintf("event numbers reset from %d to %d", gap.After, gap.Next)
+	}
+
+	e.enqueue(&Event{
+		Time: now,
+		When: now,
+		ID:   -9995, //nolint:mnd // library events use fixed negative IDs.
+		Type: EventStreamGap,
+		Msg:  string(EventStreamGap) + " " + msg,
+		Gap:  gap,
+	})
 }
 
 func (e *Events) enqueue(event *Event) {
@@ -489,33 +521,6 @@ func (e *Events) clearStream(stream io.ReadCloser) {
 	}
 }
 
-func (e *Events) callbacksFor(eventType EventType) []func(Event) {
-	e.binds.RLock()
-	defer e.binds.RUnlock()
-
-	callbacks := make([]func(Event), 0, len(e.eventBinds[eventType])+len(e.eventBinds[EventAllEvents])+1)
-	if vals, ok := e.eventBinds[eventType]; ok {
-		callbacks = append(callbacks, vals...)
-	} else if eventType != EventUnknownEvent {
-		callbacks = append(callbacks, e.eventBinds[EventUnknownEvent]...)
-	}
-
-	callbacks = append(callbacks, e.eventBinds[EventAllEvents]...)
-
-	return callbacks
-}
-
-func (e *Events) channelsFor(eventType EventType) []chan Event {
-	e.chans.RLock()
-	defer e.chans.RUnlock()
-
-	channels := make([]chan Event, 0, len(e.eventChans[eventType])+len(e.eventChans[EventAllEvents]))
-	channels = append(channels, e.eventChans[eventType]...)
-	channels = append(channels, e.eventChans[EventAllEvents]...)
-
-	return channels
-}
-
 // scanLinesCR is a custom bufio.Scanner to read SecuritySpy eventStream.
 func scanLinesCR(data []byte, atEOF bool) (int, []byte, error) {
 	if atEOF && len(data) == 0 {
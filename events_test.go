@@ -1,10 +1,16 @@
 package securityspy_test
 
 import (
+	"context"
+	"io"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+	"golift.io/securityspy/v2/server"
 )
 
 func TestUnmarshalEventWithoutInfo(t *testing.T) {
@@ -90,3 +96,355 @@ func TestUnmarshalEventClassifyPartial(t *testing.T) {
 	require.Equal(t, 95, event.ClassifyVehicle)
 	require.Equal(t, 0, event.ClassifyAnimal)
 }
+
+func TestUnmarshalEventReasonTables(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	event := sspy.Events.UnmarshalEvent("20190927092026 5 3 TRIGGER_M 513")
+	require.Equal(t, []securityspy.TriggerEvent{securityspy.TriggerByMotion, securityspy.TriggerByHomeKitEvent}, event.Reasons)
+
+	fake.SetInfo(func(info *securityspytest.ServerInfo) { info.Version = "5.5.4" })
+	require.NoError(t, sspy.Refresh())
+
+	event = sspy.Events.UnmarshalEvent("20190927092026 6 3 TRIGGER_M 1537")
+	require.Equal(t, []securityspy.TriggerEvent{securityspy.TriggerByMotion, securityspy.TriggerByAnimalDetection}, event.Reasons,
+		"bit 512 is Animal on v5")
+	require.Equal(t, 1537, event.ReasonMask)
+	require.Equal(t, 1024, event.UnknownReasons, "v5 has no bit 1024")
+	require.Equal(t, "TRIGGER_M 1537 - Reasons: Motion Detected, Animal Detected, Unknown Reason (1024)", event.Msg)
+
+	sspy.Events.SetReasonTable(securityspy.ReasonTable{1024: securityspy.TriggerByHumanArrival})
+	event = sspy.Events.UnmarshalEvent("20190927092026 7 3 TRIGGER_M 1024")
+	require.Equal(t, []securityspy.TriggerEvent{securityspy.TriggerByHumanArrival}, event.Reasons)
+	require.Zero(t, event.UnknownReasons)
+
+	sspy.Events.SetReasonTable(nil)
+	event = sspy.Events.UnmarshalEvent("20190927092026 8 3 TRIGGER_M 1024")
+	require.Empty(t, event.Reasons)
+}
+
+func TestUnmarshalEventFile(t *testing.T) {
+	t.Parallel()
+
+	secspyServer, _, _ := testServerWithCamera(t)
+	event := secspyServer.Events.UnmarshalEvent(
+		"20190726155300 6 3 FILE /Volumes/VolName/Cam/2019-07-26/26-07-2019 15-52-00 C Cam.m4v")
+
+	require.Equal(t, securityspy.EventFileWritten, event.Type)
+	require.Empty(t, event.Errors)
+	require.NotNil(t, event.File)
+	require.Equal(t, 3, event.File.CameraNum)
+	require.Equal(t, event.Camera, event.File.Camera)
+	require.Equal(t, securityspy.CaptureContinuous, event.File.Capture)
+	require.Equal(t, "m4v", event.File.Extension)
+	require.Equal(t, "video/quicktime", event.File.Link.Type)
+	require.Equal(t, "++getfile/3/2019-07-26/26-07-2019+15-52-00+C+Cam.m4v", event.File.Link.HREF)
+	require.Equal(t, "/Volumes/VolName/Cam/2019-07-26/26-07-2019 15-52-00 C Cam.m4v", event.File.Path)
+	require.Equal(t, time.Date(2019, 7, 26, 22, 52, 0, 0, time.UTC), event.File.Updated.UTC(), "server time is GMT-7")
+
+	event = secspyServer.Events.UnmarshalEvent("20190726155300 7 3 FILE /Volumes/VolName/Cam/2019-07-26/26-07-2019 15-52-00 M Cam.jpg")
+	require.Equal(t, securityspy.CaptureImage, event.File.Capture)
+	require.Equal(t, "image/jpeg", event.File.Link.Type)
+
+	event = secspyServer.Events.UnmarshalEvent("20190726155300 8 3 FILE /Volumes/nope.m4v")
+	require.Nil(t, event.File)
+	require.ErrorIs(t, event.Errors[0], securityspy.ErrInvalidName)
+}
+
+func TestFileEventDownload(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	captured := time.Date(2019, 1, 18, 10, 17, 53, 0, time.UTC)
+	fake.AddFile(securityspytest.File{CameraNum: 3, Capture: securityspytest.CaptureMotion, Time: captured})
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	event := sspy.Events.UnmarshalEvent("20190118101800 9 3 FILE /Volumes/Disk/Door/2019-01-18/01-18-2019 10-17-53 M Door.m4v")
+	require.NotNil(t, event.File)
+	require.Equal(t, securityspy.CaptureMotion, event.File.Capture)
+
+	body, err := event.File.Get(true)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "fake M file for camera 3", string(data))
+}
+
+func TestEventStreamGaps(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	events := make(chan securityspy.Event, 100)
+	sspy.Events.BindChan(securityspy.EventAllEvents, events)
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	require.Eventually(t, func() bool { return fake.EventStreamClients() == 1 }, time.Second, time.Millisecond)
+
+	next := func() securityspy.Event {
+		t.Helper()
+
+		for {
+			select {
+			case event := <-events:
+				if event.Type != securityspy.EventStreamConnect && event.Type != securityspy.EventKeepAlive {
+					return event
+				}
+			case <-time.After(time.Second):
+				t.Fatal("no event")
+			}
+		}
+	}
+
+	fake.PushEvent(3, "ARM_M")
+	fake.KeepAlive()
+	fake.PushEvent(3, "DISARM_M")
+	require.Equal(t, 1, next().ID)
+	require.Equal(t, 3, next().ID, "keep-alives are numbered too")
+
+	fake.SetEventNumber(5)
+	fake.PushEvent(3, "ARM_A")
+
+	gap := next()
+	require.Equal(t, securityspy.EventStreamGap, gap.Type)
+	require.Equal(t, securityspy.EventGap{After: 3, Next: 6, Missed: 2}, *gap.Gap)
+	require.Equal(t, 6, next().ID)
+
+	old := time.Now().Add(-time.Hour).Format(securityspy.EventTimeFormat)
+	fake.PushLine(old + " 5 3 ARM_A")
+	fake.PushLine(old + " 6 3 ARM_A")
+	fake.PushEvent(3, "DISARM_A")
+	require.Equal(t, 7, next().ID, "replayed events are dropped")
+
+	future := time.Now().Add(time.Hour).Format(securityspy.EventTimeFormat)
+	fake.PushLine(future + " 1 3 ARM_C")
+
+	gap = next()
+	require.Equal(t, securityspy.EventStreamGap, gap.Type)
+	require.True(t, gap.Gap.Reset)
+	require.Equal(t, -1, gap.Gap.Missed)
+	require.Equal(t, 1, next().ID)
+}
+
+func TestWaitConnectedStop(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+	require.ErrorIs(t, sspy.Events.WaitConnected(context.Background()), securityspy.ErrStreamStopped)
+
+	fake.Handle("/++eventStream", func(resp http.ResponseWriter, _ *http.Request) {
+		http.Error(resp, "unavailable", http.StatusInternalServerError)
+	})
+
+	sspy.Events.Watch(10*time.Millisecond, false)
+
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- sspy.Events.WaitConnected(context.Background()) }()
+
+	time.Sleep(20 * time.Millisecond)
+	sspy.Events.Stop(false)
+
+	select {
+	case err := <-waitErr:
+		require.ErrorIs(t, err, securityspy.ErrStreamStopped)
+	case <-time.After(2 * time.Second):
+		t.Fatal("WaitConnected did not return after Stop")
+	}
+}
+
+func TestEventStreamErrorRedacted(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	config := fake.Config()
+	config.Password = "c2VjcmV0OnBhc3M="
+
+	sspy, err := securityspy.New(config)
+	require.NoError(t, err)
+
+	fake.Handle("/++eventStream", func(resp http.ResponseWriter, req *http.Request) {
+		http.Error(resp, "bad auth: "+req.URL.Query().Get("auth"), http.StatusUnauthorized)
+	})
+
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+	require.Eventually(t, func() bool { return sspy.Events.Stats().LastError != nil }, time.Second, time.Millisecond)
+
+	lastErr := sspy.Events.Stats().LastError
+	require.ErrorIs(t, lastErr, server.ErrAuthFailed)
+	require.NotContains(t, lastErr.Error(), config.Password, "credentials echoed by the server are scrubbed")
+	require.Contains(t, lastErr.Error(), "bad auth: REDACTED")
+}
+
+func TestEventStreamStates(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+	require.Equal(t, securityspy.StreamStopped, sspy.Events.State())
+
+	fake.Handle("/++eventStream", func(resp http.ResponseWriter, _ *http.Request) {
+		http.Error(resp, "unavailable", http.StatusInternalServerError)
+	})
+
+	states := sspy.Events.SubscribeState(100)
+	sspy.Events.MaxRetryInterval = 40 * time.Millisecond
+	sspy.Events.Watch(10*time.Millisecond, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, sspy.Events.WaitConnected(ctx), context.DeadlineExceeded)
+
+	stats := sspy.Events.Stats()
+	require.GreaterOrEqual(t, stats.Failures, 2, "failed dials are counted")
+	require.ErrorIs(t, stats.LastError, server.ErrBadStatus, "failed dials are recorded")
+	require.Zero(t, stats.Connections)
+
+	change := <-states
+	require.Equal(t, securityspy.StreamStateChange{From: securityspy.StreamStopped, To: securityspy.StreamConnecting, Time: change.Time}, change)
+	change = <-states
+	require.Equal(t, securityspy.StreamBackoff, change.To)
+	require.Error(t, change.Err)
+
+	fake.Handle("/++eventStream", nil)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, sspy.Events.WaitConnected(ctx))
+
+	fake.PushEvent(3, "ARM_M")
+	require.Eventually(t, func() bool { return sspy.Events.Stats().Events == 1 }, time.Second, time.Millisecond)
+
+	stats = sspy.Events.Stats()
+	require.Equal(t, securityspy.StreamConnected, stats.State)
+	require.Equal(t, 1, stats.Connections)
+	require.Zero(t, stats.Failures)
+	require.False(t, stats.Connected.IsZero())
+	require.False(t, stats.LastEvent.IsZero())
+
+	fake.CloseEventStreams()
+	require.Eventually(t, func() bool { return sspy.Events.Stats().Connections == 2 }, time.Second, time.Millisecond)
+	require.ErrorIs(t, sspy.Events.Stats().LastError, securityspy.ErrDisconnect)
+	require.Zero(t, sspy.Events.Stats().Events, "stats are per connection")
+
+	sspy.Events.Stop(true)
+	require.Equal(t, securityspy.StreamStopped, sspy.Events.State())
+	require.ErrorIs(t, sspy.Events.WaitConnected(context.Background()), securityspy.ErrStreamStopped)
+
+	var last securityspy.StreamStateChange
+	for change := range states {
+		last = change
+	}
+
+	require.Equal(t, securityspy.StreamStopped, last.To, "Stop closes state subscriptions")
+}
+
+func TestEventStreamKeepAliveWatchdog(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	disconnects := make(chan securityspy.Event, 10)
+	sspy.Events.BindChan(securityspy.EventStreamDisconnect, disconnects)
+	sspy.Events.KeepAliveTimeout = 100 * time.Millisecond
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, sspy.Events.WaitConnected(ctx))
+
+	for range 6 {
+		time.Sleep(40 * time.Millisecond)
+		fake.KeepAlive()
+	}
+
+	require.Empty(t, disconnects, "keep-alives hold the connection open")
+	require.Equal(t, 1, sspy.Events.Stats().Connections)
+
+	select {
+	case event := <-disconnects:
+		require.Contains(t, event.Msg, securityspy.ErrStreamStalled.Error())
+	case <-time.After(time.Second):
+		t.Fatal("the watchdog did not drop a silent stream")
+	}
+
+	require.ErrorIs(t, sspy.Events.Stats().LastError, securityspy.ErrStreamStalled)
+	require.Eventually(t, func() bool { return sspy.Events.Stats().Connections == 2 }, time.Second, time.Millisecond)
+}
+
+func TestUnmarshalEventPayloads(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	event := sspy.Events.UnmarshalEvent("20261015201503 1 3 CLASSIFY HUMAN 91 PACKAGE 77 VEHICLE -99 BOGUS")
+	require.Equal(t, &securityspy.ClassifyPayload{
+		Scores: map[string]int{"HUMAN": 91, "PACKAGE": 77, "VEHICLE": -99},
+		Extra:  []string{"BOGUS"},
+	}, event.Payload())
+	require.Equal(t, 91, event.ClassifyHuman)
+	require.Equal(t, -99, event.ClassifyAnimal)
+	require.Equal(t, map[string]int{"HUMAN": 91, "PACKAGE": 77}, event.Record().Scores)
+
+	event = sspy.Events.UnmarshalEvent("20261015201503 2 3 TRIGGER_M 129 v2")
+	require.Equal(t, &securityspy.TriggerPayload{
+		Mask:    129,
+		Reasons: []securityspy.TriggerEvent{securityspy.TriggerByMotion, securityspy.TriggerByHumanDetection},
+		Extra:   []string{"v2"},
+	}, event.Payload())
+
+	path := "/Volumes/Cams/Door/2026-10-15/15-10-2026 20-15-03 M Door.m4v"
+	event = sspy.Events.UnmarshalEvent("20261015201506 3 3 FILE " + path)
+	payload, ok := event.Payload().(*securityspy.FilePayload)
+	require.True(t, ok)
+	require.Equal(t, path, payload.Path)
+	require.Same(t, event.File, payload.File)
+
+	event = sspy.Events.UnmarshalEvent("20261015201506 4 2 DISARM_A")
+	require.Equal(t, &securityspy.ArmPayload{Mode: securityspy.CameraModeActions}, event.Payload())
+
+	event = sspy.Events.UnmarshalEvent("20261015201506 5 X ERROR 10,-35 Disk full")
+	require.Equal(t, &securityspy.ErrorPayload{Text: "10,-35 Disk full"}, event.Payload())
+
+	event = sspy.Events.UnmarshalEvent("20261015201506 6 3 DOORBELL front 2")
+	require.Equal(t, securityspy.EventUnknownEvent, event.Type)
+	require.Equal(t, &securityspy.RawPayload{Type: "DOORBELL", Fields: []string{"front", "2"}}, event.Payload())
+
+	event = &securityspy.Event{Type: securityspy.EventStreamCustom}
+	require.Nil(t, event.Payload(), "library events have no payload")
+}
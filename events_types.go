@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,6 +27,11 @@ var (
 	ErrDateParseFail = errors.New("timestamp parse failed")
 	// ErrDisconnect becomes the msg in a custom event when the SecSpy event stream is disconnected.
 	ErrDisconnect = errors.New("server connection closed")
+	// ErrStreamStalled is the disconnect reason when the event stream sends nothing,
+	// not even a keep-alive, for Events.KeepAliveTimeout.
+	ErrStreamStalled = errors.New("event stream stalled")
+	// ErrStreamStopped is returned by Events.WaitConnected when the watcher is not running.
+	ErrStreamStopped = errors.New("event stream watcher stopped")
 )
 
 const (
@@ -45,7 +51,7 @@ const (
 // Events is the main Events interface. Use the methods bound here to bind your
 // own functions, methods and channels to SecuritySpy events. Call Watch() to
 // connect to the event stream. The Running bool is true when the event stream
-// watcher routine is active.
+// watcher routine is active; State and Stats have the details.
 type Events struct {
 	server      *Server
 	stream      io.ReadCloser
@@ -54,28 +60,73 @@ type Events struct {
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
 	mu          sync.RWMutex
-	eventBinds  map[EventType][]func(event Event)
-	eventChans  map[EventType][]chan Event
+	bindings    map[EventType][]*Binding
 	binds       sync.RWMutex
-	chans       sync.RWMutex
 	Running     bool
-	callbackSem chan struct{}
+	reasons     ReasonTable // Overrides the version-based table when not nil.
+	lastID      int         // Last event number from the stream. Only the scanner uses these two.
+	lastWhen    time.Time   // Server time of the last numbered event.
+	stateMu     sync.Mutex  // Protects the fields below.
+	stats       StreamStats
+	stateSubs   map[chan StreamStateChange]struct{}
+	stateChange chan struct{} // Closed and replaced on every state change.
+	inflight    atomic.Int64  // Events popped from binding queues and not yet finished with.
+
+	callbackCtx    context.Context //nolint:containedctx // parent of every callback's context.
+	callbackCancel context.CancelFunc
+
+	// MaxRetryInterval caps the delay between reconnect attempts. The delay starts at
+	// the retryInterval passed to Watch and doubles after each failure, with jitter.
+	// Set this before calling Watch. 0 uses DefaultMaxRetryInterval.
+	MaxRetryInterval time.Duration
+	// KeepAliveTimeout is how long the event stream may go without sending a line,
+	// keep-alives included, before the watcher drops the connection and reconnects.
+	// SecuritySpy sends a keep-alive every 10 seconds. Set this before calling Watch.
+	// 0 uses DefaultKeepAliveTimeout and a negative value disables the check.
+	KeepAliveTimeout time.Duration
 }
 
 // Event represents a SecuritySpy event from the Stream Reply.
 // This is the INPUT data for an event that is sent to a bound callback method or channel.
 type Event struct {
-	Time            time.Time      // Local time event was recorded.
-	When            time.Time      // Event time according to server.
-	ID              int            // Negative numbers are custom events.
-	Camera          *Camera        // Each event gets a camera interface.
-	Type            EventType      // Event identifier
-	Msg             string         // Event Text
-	Errors          []error        // Errors populated by parse errors.
-	Reasons         []TriggerEvent // Bitmask of trigger reasons.
-	ClassifyHuman   int            // CLASSIFY event human score (-99 when absent).
-	ClassifyVehicle int            // CLASSIFY event vehicle score (-99 when absent).
-	ClassifyAnimal  int            // CLASSIFY event animal score (-99 when absent).
+	Time            time.Time        // Local time event was received. Replay sets the recorded time.
+	When            time.Time        // Event time according to server.
+	ID              int              // Negative numbers are custom events.
+	Camera          *Camera          // Each event gets a camera interface.
+	Type            EventType        // Event identifier
+	Msg             string           // Event Text
+	Errors          []error          // Errors populated by parse errors.
+	Reasons         []TriggerEvent   // Trigger reasons decoded from the bitmask, lowest bit first.
+	ReasonMask      int              // Raw TRIGGER_M/TRIGGER_A bitmask as sent by the server.
+	UnknownReasons  int              // Bits in ReasonMask that the server's ReasonTable does not know.
+	ClassifyHuman   int              // CLASSIFY event human score (-99 when absent).
+	ClassifyVehicle int              // CLASSIFY event vehicle score (-99 when absent).
+	ClassifyAnimal  int              // CLASSIFY event animal score (-99 when absent).
+	Change          *Change          // Inventory change from Refresh; only set on EventCamera* and EventServerChange.
+	File            *File            // Saved file from a FILE event, ready to Get or Save.
+	Gap             *EventGap        // Missing event numbers; only set on EventStreamGap.
+	Dropped         *DropReport      // Bindings that dropped events; only set on EventDeliveryDropped.
+	Failure         *CallbackFailure // Failed callback; only set on EventCallbackPanic and EventCallbackTimeout.
+	payload         Payload          // Parsed event body from UnmarshalEvent. See Payload.
+}
+
+// EventGap describes event numbers the stream skipped, ie. while it was disconnected.
+type EventGap struct {
+	After  int  // Last event number received before the gap.
+	Next   int  // First event number received after the gap.
+	Missed int  // Number of events missed. -1 if the server reset its numbering, so it is unknown.
+	Reset  bool // The numbering went backwards with newer events, ie. SecuritySpy restarted.
+}
+
+// Change is one difference between two inventory snapshots, found by Refresh.
+// Each change is also sent to Events bindings as an event of the same Type.
+type Change struct {
+	Type     EventType // EventCameraAdded, EventServerChange, etc.
+	Camera   *Camera   // Camera from the new snapshot, or the old one if it was removed. Nil for server changes.
+	Previous *Camera   // Camera from the old snapshot. Nil if it was added or for server changes.
+	Field    string    // Field that changed, ie. Name, Number, Connected, ModeM, ScheduleIDCC, Groups or Version.
+	Old      string    // Previous value; empty for added cameras.
+	New      string    // Current value; empty for removed cameras.
 }
 
 // EventType is a set of constant strings validated by the EventNames map.
@@ -113,6 +164,22 @@ const (
 	EventWatcherRefreshed   EventType = "REFRESH"
 	EventWatcherRefreshFail EventType = "REFRESHFAIL"
 	EventStreamCustom       EventType = "CUSTOM"
+	EventStreamGap          EventType = "GAP"     // Event numbers were skipped. Event.Gap has the range.
+	EventDeliveryDropped    EventType = "DROPPED" // Bindings dropped events. Event.Dropped has the counts.
+	EventCallbackPanic      EventType = "PANIC"   // A callback panicked. Event.Failure has the details.
+	EventCallbackTimeout    EventType = "TIMEOUT" // A callback ran past BindConfig.Timeout. Event.Failure has the details.
+
+	// Inventory changes found by Refresh. Event.Change has the details.
+
+	EventCameraAdded       EventType = "CAMERA_ADDED"
+	EventCameraRemoved     EventType = "CAMERA_REMOVED"
+	EventCameraRenamed     EventType = "CAMERA_RENAMED"
+	EventCameraRenumbered  EventType = "CAMERA_RENUMBERED"
+	EventCameraConnection  EventType = "CAMERA_CONNECTION"
+	EventCameraModeChange  EventType = "CAMERA_MODE"
+	EventCameraSchedule    EventType = "CAMERA_SCHEDULE"
+	EventCameraGroupChange EventType = "CAMERA_GROUPS"
+	EventServerChange      EventType = "SERVER_CHANGE"
 )
 
 // EventName returns the human readable names for each event.
@@ -143,20 +210,31 @@ func EventName(eventType EventType) string {
 		EventWatcherRefreshed:   "SystemInfo Refresh Success",
 		EventWatcherRefreshFail: "SystemInfo Refresh Failure",
 		EventStreamCustom:       "Custom Event",
+		EventStreamGap:          "Event Stream Gap",
+		EventDeliveryDropped:    "Events Dropped",
+		EventCallbackPanic:      "Callback Panicked",
+		EventCallbackTimeout:    "Callback Timed Out",
+		EventCameraAdded:        "Camera Added",
+		EventCameraRemoved:      "Camera Removed",
+		EventCameraRenamed:      "Camera Renamed",
+		EventCameraRenumbered:   "Camera Renumbered",
+		EventCameraConnection:   "Camera Connection Changed",
+		EventCameraModeChange:   "Camera Mode Changed",
+		EventCameraSchedule:     "Camera Schedule Changed",
+		EventCameraGroupChange:  "Camera Groups Changed",
+		EventServerChange:       "Server Info Changed",
 	}[eventType]
 }
 
 // TriggerEvent represent the "Reason" a motion or action trigger occurred. v5+ only.
 type TriggerEvent int
 
-// Trigger reason bitmasks for TRIGGER_M and TRIGGER_A events.
+// Trigger reasons for TRIGGER_M and TRIGGER_A events.
 //
-// These constants follow the SecuritySpy v6 web-server specification. On SecuritySpy v5
-// the table stops at Animal: bit 512 means Animal detection, not HomeKit. v6 inserts
-// HomeKit at 512 and shifts Animal to 1024, then adds arrival/departure bits above that.
-// This library keeps the v6 layout so v6 servers decode correctly; v5 servers that emit
-// bit 512 for animal motion will surface TriggerByHomeKitEvent in Reasons() even though
-// the server meant Animal — check Classify* fields or raw Msg on v5 when that matters.
+// The values follow the SecuritySpy v6 bit layout. Servers do not all use that layout:
+// on v5 the table stops at Animal, and bit 512 means Animal, not HomeKit. UnmarshalEvent
+// decodes the bitmask with a ReasonTable picked by server version, so Event.Reasons holds
+// these constants on every version. Use Events.SetReasonTable for servers it gets wrong.
 const (
 	TriggerByMotion = TriggerEvent(1) << iota
 	TriggerByAudio
@@ -167,9 +245,9 @@ const (
 	TriggerByManual
 	TriggerByHumanDetection
 	TriggerByVehicleDetection
-	// TriggerByHomeKitEvent is 512 on v6. On SecuritySpy v5, bit 512 is Animal detection.
+	// TriggerByHomeKitEvent is bit 512 on v6. It does not exist on v5.
 	TriggerByHomeKitEvent
-	// TriggerByAnimalDetection is 1024 on v6. On SecuritySpy v5, Animal is bit 512 instead.
+	// TriggerByAnimalDetection is bit 1024 on v6, and bit 512 on v5.
 	TriggerByAnimalDetection
 	TriggerByHumanArrival
 	TriggerByHumanDeparture
@@ -205,3 +283,41 @@ func Reasons() map[TriggerEvent]string {
 func (reason TriggerEvent) String() string {
 	return Reasons()[reason]
 }
+
+// ReasonTable maps the bits of a TRIGGER_M or TRIGGER_A bitmask to trigger reasons.
+// Bits missing from the table end up in Event.UnknownReasons.
+type ReasonTable map[int]TriggerEvent
+
+// ReasonTableV5 returns the trigger reason bits used by SecuritySpy v5 and older.
+func ReasonTableV5() ReasonTable {
+	table := ReasonTable{}
+
+	for reason := TriggerByMotion; reason <= TriggerByVehicleDetection; reason <<= 1 {
+		table[int(reason)] = reason
+	}
+
+	table[int(TriggerByHomeKitEvent)] = TriggerByAnimalDetection
+
+	return table
+}
+
+// ReasonTableV6 returns the trigger reason bits used by SecuritySpy v6.
+func ReasonTableV6() ReasonTable {
+	table := ReasonTable{}
+
+	for reason := range Reasons() {
+		table[int(reason)] = reason
+	}
+
+	return table
+}
+
+// ReasonTableFor returns the reason table for a server version.
+// Unknown (zero) versions get the v6 table.
+func ReasonTableFor(version Version) ReasonTable {
+	if !version.IsZero() && !version.AtLeast(6, 0) { //nolint:mnd
+		return ReasonTableV5()
+	}
+
+	return ReasonTableV6()
+}
@@ -0,0 +1,201 @@
+// Package exporter serves SecuritySpy server, camera and event metrics
+// in the Prometheus text exposition format, without any Prometheus dependencies.
+//
+//	exp := exporter.New(sspy, nil)
+//	exp.BindEvents()
+//	sspy.Events.Watch(time.Second*10, true)
+//	http.Handle("/metrics", exp)
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golift.io/securityspy/v2"
+)
+
+const (
+	// DefaultNamespace prefixes every metric name.
+	DefaultNamespace = "securityspy"
+	// DefaultMaxAge is how old the ++systemInfo data may be before a scrape refreshes it.
+	DefaultMaxAge = 30 * time.Second
+	// contentType is the Prometheus text exposition format.
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// scoreBuckets are the upper bounds for the classification score histogram. Scores are 0-100.
+var scoreBuckets = []float64{10, 25, 50, 75, 90, 100} //nolint:gochecknoglobals
+
+// Config is optional input for New.
+type Config struct {
+	// Namespace prefixes every metric name. Defaults to DefaultNamespace.
+	Namespace string
+	// MaxAge is how old server and camera data may get before a scrape calls ++systemInfo.
+	// Scrapes arriving sooner reuse the data from the last refresh, including refreshes done
+	// by Events.Watch on config changes. 0 uses DefaultMaxAge. Negative never refreshes during
+	// a scrape; use that if your app already calls Refresh on its own schedule.
+	MaxAge time.Duration
+}
+
+// Exporter collects metrics from a SecuritySpy server. It is an http.Handler.
+type Exporter struct {
+	server    *securityspy.Server
+	namespace string
+	maxAge    time.Duration
+	refresh   sync.Mutex // Serializes scrape-time refreshes.
+	mu        sync.Mutex // Protects the fields below.
+	lastErr   error
+	refreshes map[bool]float64
+	events    map[eventKey]float64
+	reasons   map[reasonKey]float64
+	scores    map[scoreKey]*histogram
+}
+
+type eventKey struct {
+	event  securityspy.EventType
+	camera string
+}
+
+type reasonKey struct {
+	event  securityspy.EventType
+	camera string
+	reason string
+}
+
+type scoreKey struct {
+	camera string
+	class  string
+}
+
+type histogram struct {
+	buckets []float64 // Cumulative counts, one per scoreBuckets entry.
+	sum     float64
+	count   float64
+}
+
+// New returns an exporter for a server. Config may be nil.
+// The server should already be refreshed, or have a positive MaxAge.
+func New(server *securityspy.Server, config *Config) *Exporter {
+	if config == nil {
+		config = &Config{}
+	}
+
+	exp := &Exporter{
+		server:    server,
+		namespace: config.Namespace,
+		maxAge:    config.MaxAge,
+		refreshes: make(map[bool]float64),
+		events:    make(map[eventKey]float64),
+		reasons:   make(map[reasonKey]float64),
+		scores:    make(map[scoreKey]*histogram),
+	}
+
+	if exp.namespace == "" {
+		exp.namespace = DefaultNamespace
+	}
+
+	if exp.maxAge == 0 {
+		exp.maxAge = DefaultMaxAge
+	}
+
+	return exp
+}
+
+// BindEvents binds Observe to every event on the server's event stream.
+// Call this before Events.Watch to collect event metrics.
+func (e *Exporter) BindEvents() {
+	e.server.Events.BindFunc(securityspy.EventAllEvents, e.Observe)
+}
+
+// Observe counts an event. BindEvents feeds every event here; call it directly
+// if you consume events some other way. Keep-alive (NULL) events are not counted.
+func (e *Exporter) Observe(event securityspy.Event) {
+	if event.Type == securityspy.EventKeepAlive {
+		return
+	}
+
+	camera := ""
+	if event.Camera != nil {
+		camera = strconv.Itoa(event.Camera.Number)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.events[eventKey{event: event.Type, camera: camera}]++
+
+	for _, reason := range event.Reasons {
+		e.reasons[reasonKey{event: event.Type, camera: camera, reason: reason.String()}]++
+	}
+
+	if event.Type != securityspy.EventClassify {
+		return
+	}
+
+	for class, score := range map[string]int{
+		"human":   event.ClassifyHuman,
+		"vehicle": event.ClassifyVehicle,
+		"animal":  event.ClassifyAnimal,
+	} {
+		if score >= 0 { // -99 means the class was not in the event.
+			e.observeScore(scoreKey{camera: camera, class: class}, float64(score))
+		}
+	}
+}
+
+func (e *Exporter) observeScore(key scoreKey, score float64) {
+	hist := e.scores[key]
+	if hist == nil {
+		hist = &histogram{buckets: make([]float64, len(scoreBuckets))}
+		e.scores[key] = hist
+	}
+
+	for idx, bound := range scoreBuckets {
+		if score <= bound {
+			hist.buckets[idx]++
+		}
+	}
+
+	hist.sum += score
+	hist.count++
+}
+
+// ServeHTTP refreshes server data if it is older than MaxAge and writes every metric.
+func (e *Exporter) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	e.Refresh(req.Context())
+
+	var buf bytes.Buffer
+
+	e.write(&buf)
+
+	resp.Header().Set("Content-Type", contentType)
+	_, _ = resp.Write(buf.Bytes())
+}
+
+// Refresh calls ++systemInfo through the server's Poller if the cached data is older than
+// MaxAge. Concurrent scrapes, and the Poller's own refreshes, share one refresh.
+// The result is exported as the up metric and recorded in the Poller's Health.
+func (e *Exporter) Refresh(ctx context.Context) {
+	if e.maxAge < 0 {
+		return
+	}
+
+	e.refresh.Lock()
+	defer e.refresh.Unlock()
+
+	if time.Since(e.server.Snapshot().Info.Refreshed) < e.maxAge {
+		return
+	}
+
+	err := e.server.Poller.Refresh(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.lastErr = err
+	e.refreshes[err == nil]++
+}
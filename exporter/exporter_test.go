@@ -0,0 +1,147 @@
+package exporter_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/exporter"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+func scrape(t *testing.T, exp http.Handler) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	exp.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestExporterServerAndCameras(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	defer fake.Close()
+
+	fake.SetInfo(func(info *securityspytest.ServerInfo) {
+		info.CPUUsage = 37
+		info.MemoryPressure = 12
+		info.CertExpiryDays = 45
+		info.NewVersion = "6.21"
+	})
+	fake.UpdateCamera(3, func(cam *securityspytest.Camera) {
+		cam.CurrentFPS = 14.5
+		cam.DataRate = 900
+		cam.TimeSinceLastFrame = 2
+		cam.LastError = 7
+	})
+
+	sspy := securityspy.NewMust(fake.Config())
+	body := scrape(t, exporter.New(sspy, &exporter.Config{MaxAge: time.Hour}))
+
+	for _, line := range []string{
+		"# TYPE securityspy_up gauge",
+		"securityspy_up 1",
+		`securityspy_info{name="SecuritySpy",version="6.20",uuid="FAKEUUID000000000001",server_name=`,
+		"securityspy_cpu_usage_percent 37",
+		"securityspy_memory_pressure_percent 12",
+		"securityspy_cert_expiry_days 45",
+		"securityspy_cameras 2",
+		"securityspy_update_available 1",
+		`securityspy_camera_connected{camera="2",name="Porch"} 0`,
+		`securityspy_camera_connected{camera="3",name="Door"} 1`,
+		`securityspy_camera_fps{camera="3",name="Door"} 14.5`,
+		`securityspy_camera_data_rate{camera="3",name="Door"} 900`,
+		`securityspy_camera_last_frame_seconds{camera="3",name="Door"} 2`,
+		`securityspy_camera_last_error{camera="3",name="Door"} 7`,
+		`securityspy_camera_armed{camera="3",name="Door",mode="motion"} 1`,
+		`securityspy_camera_armed{camera="3",name="Door",mode="continuous"} 0`,
+		`securityspy_refreshes_total{result="success"} 1`,
+	} {
+		require.Contains(t, body, line)
+	}
+
+	// The second scrape reuses the cached systemInfo.
+	fake.UpdateCamera(2, func(cam *securityspytest.Camera) { cam.NoMotionYet = true })
+	body = scrape(t, exporter.New(sspy, &exporter.Config{MaxAge: time.Hour}))
+	require.Equal(t, 1, fake.CountRequests("/++systemInfo"))
+	require.Contains(t, body, `securityspy_camera_last_motion_seconds{camera="2"`)
+
+	require.NoError(t, sspy.Refresh())
+	body = scrape(t, exporter.New(sspy, &exporter.Config{MaxAge: time.Hour}))
+	require.Contains(t, body, `securityspy_camera_last_motion_seconds{camera="3",name="Door"}`)
+	require.NotContains(t, body, `securityspy_camera_last_motion_seconds{camera="2"`, "empty durations are not exported")
+}
+
+func TestExporterRefreshFailure(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	defer fake.Close()
+
+	sspy := securityspy.NewMust(fake.Config())
+	exp := exporter.New(sspy, &exporter.Config{MaxAge: time.Nanosecond, Namespace: "spy"})
+
+	require.Contains(t, scrape(t, exp), "spy_up 1\n")
+
+	fake.Handle("/++systemInfo", func(resp http.ResponseWriter, _ *http.Request) {
+		http.Error(resp, "nope", http.StatusForbidden)
+	})
+
+	body := scrape(t, exp)
+	require.Contains(t, body, "spy_up 0\n")
+	require.Contains(t, body, `spy_refreshes_total{result="error"} 1`)
+	require.Contains(t, body, `spy_camera_connected{camera="3",name="Door"} 1`, "stale camera data is still exported")
+	require.Equal(t, 1, sspy.Poller.Health().ConsecutiveFailures, "scrape refreshes go through the Poller")
+}
+
+func TestExporterEvents(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	defer fake.Close()
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	exp := exporter.New(sspy, &exporter.Config{MaxAge: -1})
+	door := sspy.Snapshot().Cameras.ByNum(3)
+
+	exp.Observe(securityspy.Event{Type: securityspy.EventTriggerMotion, Camera: door,
+		Reasons: []securityspy.TriggerEvent{securityspy.TriggerByMotion, securityspy.TriggerByHumanDetection}})
+	exp.Observe(securityspy.Event{Type: securityspy.EventTriggerMotion, Camera: door,
+		Reasons: []securityspy.TriggerEvent{securityspy.TriggerByMotion}})
+	exp.Observe(securityspy.Event{Type: securityspy.EventClassify, Camera: door,
+		ClassifyHuman: 80, ClassifyVehicle: 5, ClassifyAnimal: -99})
+	exp.Observe(securityspy.Event{Type: securityspy.EventKeepAlive})
+
+	body := scrape(t, exp)
+
+	for _, line := range []string{
+		`securityspy_events_total{type="TRIGGER_M",camera="3"} 2`,
+		`securityspy_trigger_reasons_total{type="TRIGGER_M",camera="3",reason="Motion Detected"} 2`,
+		`securityspy_trigger_reasons_total{type="TRIGGER_M",camera="3",reason="Human Detected"} 1`,
+		`securityspy_classify_score_bucket{camera="3",class="human",le="75"} 0`,
+		`securityspy_classify_score_bucket{camera="3",class="human",le="90"} 1`,
+		`securityspy_classify_score_bucket{camera="3",class="vehicle",le="10"} 1`,
+		`securityspy_classify_score_bucket{camera="3",class="human",le="+Inf"} 1`,
+		`securityspy_classify_score_sum{camera="3",class="human"} 80`,
+		"# TYPE securityspy_classify_score histogram",
+	} {
+		require.Contains(t, body, line)
+	}
+
+	require.NotContains(t, body, `class="animal"`, "absent scores are not observed")
+	require.NotContains(t, body, `type="NULL"`, "keep-alives are not counted")
+	require.Equal(t, 1, fake.CountRequests("/++systemInfo"), "negative MaxAge never refreshes")
+}
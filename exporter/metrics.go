@@ -0,0 +1,193 @@
+package exporter
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// metric is one metric family in the text exposition format.
+type metric struct {
+	name    string
+	help    string
+	kind    string // gauge, counter or histogram.
+	samples []sample
+}
+
+type sample struct {
+	suffix string   // Appended to the name, ie. _bucket.
+	labels []string // Pairs of label name and value.
+	value  float64
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	m.samples = append(m.samples, sample{labels: labels, value: value})
+}
+
+// write renders every metric family to w.
+func (e *Exporter) write(w io.Writer) {
+	metrics := e.serverMetrics()
+	metrics = append(metrics, e.cameraMetrics()...)
+	metrics = append(metrics, e.eventMetrics()...)
+
+	for _, m := range metrics {
+		name := e.namespace + "_" + m.name
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, m.help, name, m.kind)
+
+		for _, s := range m.samples {
+			fmt.Fprintf(w, "%s%s%s %s\n", name, s.suffix, formatLabels(s.labels), formatValue(s.value))
+		}
+	}
+}
+
+func (e *Exporter) serverMetrics() []*metric {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	snap := e.server.Snapshot()
+	info := snap.Info
+
+	up := &metric{name: "up", help: "Whether the last ++systemInfo refresh succeeded.", kind: "gauge"}
+	up.add(boolValue(e.lastErr == nil && !info.Refreshed.IsZero()))
+
+	refreshes := &metric{name: "refreshes_total", help: "Scrape-time ++systemInfo refreshes.", kind: "counter"}
+	refreshes.add(e.refreshes[true], "result", "success")
+	refreshes.add(e.refreshes[false], "result", "error")
+
+	serverInfo := &metric{name: "info", help: "SecuritySpy server information.", kind: "gauge"}
+	serverInfo.add(1, "name", info.Name, "version", info.Version, "uuid", info.UUID, "server_name", info.ServerName)
+
+	refreshed := &metric{name: "last_refresh_timestamp_seconds", help: "When server data was last refreshed.", kind: "gauge"}
+	if !info.Refreshed.IsZero() {
+		refreshed.add(float64(info.Refreshed.UnixMilli()) / 1000) //nolint:mnd
+	}
+
+	cameras := info.CameraCount
+	if cameras == 0 {
+		cameras = len(snap.Cameras.All())
+	}
+
+	return []*metric{
+		up, refreshes, serverInfo, refreshed,
+		gauge("cpu_usage_percent", "Server CPU usage.", float64(info.CPUUsage)),
+		gauge("memory_pressure_percent", "Server memory pressure.", float64(info.MemoryPressure)),
+		gauge("cert_expiry_days", "Days until the server TLS certificate expires.", float64(info.CertExpiryDays)),
+		gauge("cameras", "Number of cameras on the server.", float64(cameras)),
+		gauge("update_available", "Whether a newer SecuritySpy version is available.", boolValue(info.NewVersion != "")),
+	}
+}
+
+func (e *Exporter) cameraMetrics() []*metric {
+	connected := &metric{name: "camera_connected", help: "Whether the camera is connected.", kind: "gauge"}
+	fps := &metric{name: "camera_fps", help: "Current camera frame rate.", kind: "gauge"}
+	rate := &metric{name: "camera_data_rate", help: "Current camera data rate, as reported by SecuritySpy.", kind: "gauge"}
+	frame := &metric{name: "camera_last_frame_seconds", help: "Seconds since the last frame.", kind: "gauge"}
+	motion := &metric{name: "camera_last_motion_seconds", help: "Seconds since the last motion.", kind: "gauge"}
+	lastErr := &metric{name: "camera_last_error", help: "Last camera error code, 0 for none.", kind: "gauge"}
+	armed := &metric{name: "camera_armed", help: "Whether a camera mode is armed.", kind: "gauge"}
+
+	for _, cam := range e.server.Snapshot().Cameras.All() {
+		labels := []string{"camera", strconv.Itoa(cam.Number), "name", cam.Name}
+
+		connected.add(boolValue(cam.Connected.Val), labels...)
+		fps.add(cam.CurrentFPS, labels...)
+		rate.add(float64(cam.DataRate), labels...)
+
+		// -1ns means SecuritySpy left the field empty, ie. no motion since it started.
+		if cam.TimeSinceLastFrame.Duration != -1 {
+			frame.add(cam.TimeSinceLastFrame.Seconds(), labels...)
+		}
+
+		if cam.TimeSinceLastMotion.Duration != -1 {
+			motion.add(cam.TimeSinceLastMotion.Seconds(), labels...)
+		}
+
+		lastErr.add(float64(cam.LastError), labels...)
+		armed.add(boolValue(cam.ModeC.Val), append(labels, "mode", "continuous")...)
+		armed.add(boolValue(cam.ModeM.Val), append(labels, "mode", "motion")...)
+		armed.add(boolValue(cam.ModeA.Val), append(labels, "mode", "actions")...)
+	}
+
+	return []*metric{connected, fps, rate, frame, motion, lastErr, armed}
+}
+
+func (e *Exporter) eventMetrics() []*metric {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	events := &metric{name: "events_total", help: "Events received, by type and camera.", kind: "counter"}
+	for _, key := range slices.SortedFunc(maps.Keys(e.events), func(a, b eventKey) int {
+		return cmp.Or(cmp.Compare(a.event, b.event), cmp.Compare(a.camera, b.camera))
+	}) {
+		events.add(e.events[key], "type", string(key.event), "camera", key.camera)
+	}
+
+	reasons := &metric{name: "trigger_reasons_total", help: "Trigger reasons seen in trigger events.", kind: "counter"}
+	for _, key := range slices.SortedFunc(maps.Keys(e.reasons), func(a, b reasonKey) int {
+		return cmp.Or(cmp.Compare(a.event, b.event), cmp.Compare(a.camera, b.camera), cmp.Compare(a.reason, b.reason))
+	}) {
+		reasons.add(e.reasons[key], "type", string(key.event), "camera", key.camera, "reason", key.reason)
+	}
+
+	scores := &metric{name: "classify_score", help: "Classification scores from CLASSIFY events.", kind: "histogram"}
+	for _, key := range slices.SortedFunc(maps.Keys(e.scores), func(a, b scoreKey) int {
+		return cmp.Or(cmp.Compare(a.camera, b.camera), cmp.Compare(a.class, b.class))
+	}) {
+		hist := e.scores[key]
+		labels := []string{"camera", key.camera, "class", key.class}
+
+		for idx, bound := range scoreBuckets {
+			scores.samples = append(scores.samples, sample{
+				suffix: "_bucket", labels: append(slices.Clone(labels), "le", formatValue(bound)), value: hist.buckets[idx],
+			})
+		}
+
+		scores.samples = append(scores.samples,
+			sample{suffix: "_bucket", labels: append(slices.Clone(labels), "le", "+Inf"), value: hist.count},
+			sample{suffix: "_sum", labels: labels, value: hist.sum},
+			sample{suffix: "_count", labels: labels, value: hist.count},
+		)
+	}
+
+	return []*metric{events, reasons, scores}
+}
+
+func gauge(name, help string, value float64) *metric {
+	m := &metric{name: name, help: help, kind: "gauge"}
+	m.add(value)
+
+	return m
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// labelEscaper escapes label values per the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`) //nolint:gochecknoglobals
+
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels)/2) //nolint:mnd
+
+	for idx := 0; idx+1 < len(labels); idx += 2 {
+		pairs = append(pairs, labels[idx]+`="`+labelEscaper.Replace(labels[idx+1])+`"`)
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -34,6 +35,16 @@ var (
 	ErrInvalidName = errors.New("invalid file name")
 )
 
+// CaptureType is the letter SecuritySpy puts in saved file names for the kind of capture.
+type CaptureType rune
+
+// Capture types found in saved file names.
+const (
+	CaptureMotion     CaptureType = 'M'
+	CaptureContinuous CaptureType = 'C'
+	CaptureImage      CaptureType = 'I'
+)
+
 // Files powers the Files interface.
 // Use the bound methods to list and download saved media files.
 type Files struct {
@@ -65,6 +76,9 @@ type File struct {
 	CameraNum int           `xml:"cameraNum"` // 0, 1, 2, 4, 5, 7, 9, 10, 11, 12, 13
 	GmtOffset time.Duration // the rest are copied in per-file from fileFeed.
 	Camera    *Camera
+	Capture   CaptureType // From the file name. Image files are always CaptureImage.
+	Extension string      // From the file name, without the dot: m4v, mov, jpg.
+	Path      string      // Full path on the server. Only set on files from FILE events.
 	server    *Server
 }
 
@@ -103,10 +117,11 @@ func (f *Files) GetFile(name string) (*File, error) {
 	//	01-18-2019 10-17-53 M Porch.m4v => ++getfile/0/2019-01-18/01-18-2019+10-17-53+M+Porch.m4v
 	var err error
 
+	snap := f.server.Snapshot()
 	file := &File{
 		Title:     name,
 		server:    f.server,
-		GmtOffset: f.server.Info.GmtOffset.Duration,
+		GmtOffset: snap.Info.GmtOffset.Duration,
 	}
 
 	if fileExtSplit := strings.Split(name, "."); len(fileExtSplit) != fileParts {
@@ -115,7 +130,7 @@ func (f *Files) GetFile(name string) (*File, error) {
 		return file, ErrInvalidName
 	} else if file.Updated, err = time.Parse(FileDateFormat, nameDateSplit[0]); err != nil {
 		return file, ErrInvalidName
-	} else if file.Camera = f.server.Cameras.ByName(nameDateSplit[len(nameDateSplit)-1]); file.Camera == nil {
+	} else if file.Camera = snap.Cameras.ByName(nameDateSplit[len(nameDateSplit)-1]); file.Camera == nil {
 		return file, ErrCAMMissing
 	} else if file.Link.Type = "video/quicktime"; fileExtSplit[1] == "jpg" {
 		file.Link.Type = "image/jpeg"
@@ -124,6 +139,44 @@ func (f *Files) GetFile(name string) (*File, error) {
 	file.CameraNum = file.Camera.Number
 	file.Link.HREF = "++getfile/" + strconv.Itoa(file.CameraNum) + "/" +
 		file.Updated.Format(DownloadDateFormat) + "/" + url.QueryEscape(name)
+	file.setCapture()
+
+	return file, nil
+}
+
+// fromPath turns the server path from a FILE event into a File, ie.
+// /Volumes/VolName/Cam/2019-07-26/26-07-2019 15-52-00 C Cam.m4v => ++getfile/3/2019-07-26/26-07-2019+15-52-00+C+Cam.m4v
+// The date in the file name follows the server's date format, so the date comes from the folder name.
+func (f *Files) fromPath(snap *Snapshot, cameraNum int, serverPath string) (*File, error) {
+	const nameParts = 4 // date, time, capture type, camera name.
+
+	dir, name := path.Split(serverPath)
+	folder := path.Base(dir)
+	parts := strings.SplitN(strings.TrimSuffix(name, path.Ext(name)), " ", nameParts)
+
+	if len(parts) != nameParts || len(parts[2]) != 1 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidName, serverPath)
+	}
+
+	zone := time.FixedZone("", int(snap.Info.GmtOffset.Seconds()))
+
+	updated, err := time.ParseInLocation(DownloadDateFormat+" 15-04-05", folder+" "+parts[1], zone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidName, serverPath)
+	}
+
+	file := &File{
+		Title:     name,
+		Updated:   updated,
+		CameraNum: cameraNum,
+		Camera:    snap.Cameras.ByNum(cameraNum),
+		GmtOffset: snap.Info.GmtOffset.Duration,
+		Path:      serverPath,
+		server:    f.server,
+	}
+
+	file.Link.HREF = "++getfile/" + strconv.Itoa(cameraNum) + "/" + folder + "/" + url.QueryEscape(name)
+	file.setCapture()
 
 	return file, nil
 }
@@ -178,6 +231,26 @@ func (f *File) Get(highBandwidth bool) (io.ReadCloser, error) {
 
 /* INTERFACE HELPER METHODS FOLLOW */
 
+// setCapture fills in the capture type, extension and content type from the file name.
+func (f *File) setCapture() {
+	f.Extension = strings.TrimPrefix(strings.ToLower(path.Ext(f.Title)), ".")
+
+	if parts := strings.SplitN(f.Title, " ", 4); len(parts) == 4 && len(parts[2]) == 1 { //nolint:mnd
+		f.Capture = CaptureType(parts[2][0])
+	}
+
+	if f.Extension == "jpg" || f.Extension == "jpeg" {
+		f.Capture = CaptureImage
+	}
+
+	if f.Link.Type == "" {
+		f.Link.Type = "video/quicktime"
+		if f.Capture == CaptureImage {
+			f.Link.Type = "image/jpeg"
+		}
+	}
+}
+
 // getFiles is a helper function to do all the work for GetVideos, GetPhotos & GetAll.
 func (f *Files) getFiles(cameraNums []int, start, end time.Time, fileTypes, continuation string) ([]*File, error) {
 	var (
@@ -190,11 +263,14 @@ func (f *Files) getFiles(cameraNums []int, start, end time.Time, fileTypes, cont
 		return nil, fmt.Errorf("getting download: %w", err)
 	}
 
+	cameras := f.server.Snapshot().Cameras
+
 	for i := range feed.Entries {
 		// Add the camera, server and file interfaces to every file entry.
-		feed.Entries[i].Camera = f.server.Cameras.ByNum(feed.Entries[i].CameraNum)
+		feed.Entries[i].Camera = cameras.ByNum(feed.Entries[i].CameraNum)
 		feed.Entries[i].server = f.server
 		feed.Entries[i].GmtOffset = feed.GmtOffset.Duration
+		feed.Entries[i].setCapture()
 		entries = append(entries, feed.Entries[i])
 	}
 
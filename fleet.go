@@ -0,0 +1,378 @@
+package securityspy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewFleet returns an empty fleet. Add servers to it with Add.
+func NewFleet() *Fleet {
+	return &Fleet{
+		servers: make(map[string]*fleetMember),
+		subs:    make(map[chan FleetEvent]struct{}),
+	}
+}
+
+// Add puts a server in the fleet under a name. The name is the server part of camera addresses.
+// The server's events are forwarded to fleet subscribers from now on; call Watch to connect them.
+func (f *Fleet) Add(name string, server *Server) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("%w: %q", ErrFleetServerName, name)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.servers[name]; ok {
+		return fmt.Errorf("%w: %s", ErrFleetServerExists, name)
+	}
+
+	member := &fleetMember{name: name, server: server, stop: make(chan struct{}), done: make(chan struct{})}
+	events := make(chan Event, FleetEventBuffer)
+	member.binding = server.Events.BindChanConfig(EventAllEvents, events, nil)
+
+	f.servers[name] = member
+	f.order = append(f.order, name)
+
+	go f.forward(member, events)
+
+	return nil
+}
+
+// Remove takes a server out of the fleet, unbinds the fleet from its events and stops
+// forwarding them. It does not stop the server's event stream; call server.Events.Stop for that.
+func (f *Fleet) Remove(name string) {
+	f.mu.Lock()
+
+	member, ok := f.servers[name]
+	if ok {
+		delete(f.servers, name)
+		f.order = slices.DeleteFunc(f.order, func(n string) bool { return n == name })
+	}
+
+	f.mu.Unlock()
+
+	if ok {
+		member.close()
+	}
+}
+
+// Names returns the fleet names of every server, in the order they were added.
+func (f *Fleet) Names() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return slices.Clone(f.order)
+}
+
+// Server returns a server by fleet name or UUID. Returns nil if it is not in the fleet.
+func (f *Fleet) Server(id string) *Server {
+	if member := f.member(id); member != nil {
+		return member.server
+	}
+
+	return nil
+}
+
+func (f *Fleet) member(id string) *fleetMember {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if member, ok := f.servers[id]; ok {
+		return member
+	}
+
+	for _, member := range f.servers {
+		if uuid := member.server.Snapshot().Info.UUID; uuid != "" && uuid == id {
+			return member
+		}
+	}
+
+	return nil
+}
+
+// members returns every member in the order they were added.
+func (f *Fleet) members() []*fleetMember {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	members := make([]*fleetMember, len(f.order))
+	for idx, name := range f.order {
+		members[idx] = f.servers[name]
+	}
+
+	return members
+}
+
+// Refresh refreshes every server concurrently through its Poller, so each server's Health
+// is kept. Returns a *FleetError keyed by server name if any fail.
+func (f *Fleet) Refresh(ctx context.Context) error {
+	return f.each(ctx, func(ctx context.Context, member *fleetMember) error {
+		return member.server.Poller.Refresh(ctx)
+	})
+}
+
+// Camera returns the camera at an address: "server/camera", where camera is a name or number.
+func (f *Fleet) Camera(address string) (FleetCamera, error) {
+	serverID, camID, ok := strings.Cut(address, "/")
+	if !ok || camID == "" {
+		return FleetCamera{}, fmt.Errorf("%w: %q", ErrCameraNotFound, address)
+	}
+
+	member := f.member(serverID)
+	if member == nil {
+		return FleetCamera{}, fmt.Errorf("%w: %s", ErrFleetServerNotFound, serverID)
+	}
+
+	cameras := member.server.Snapshot().Cameras
+
+	camera := cameras.ByName(camID)
+	if num, err := strconv.Atoi(camID); err == nil && camera == nil {
+		camera = cameras.ByNum(num)
+	}
+
+	if camera == nil {
+		return FleetCamera{}, fmt.Errorf("%w: %q", ErrCameraNotFound, address)
+	}
+
+	return member.camera(camera), nil
+}
+
+// Cameras returns every camera on every server, in server order then camera order.
+func (f *Fleet) Cameras() []FleetCamera {
+	var list []FleetCamera
+
+	for _, member := range f.members() {
+		for _, camera := range member.server.Snapshot().Cameras.All() {
+			list = append(list, member.camera(camera))
+		}
+	}
+
+	return list
+}
+
+func (m *fleetMember) camera(camera *Camera) FleetCamera {
+	return FleetCamera{Camera: camera, Server: m.name, UUID: m.server.Snapshot().Info.UUID}
+}
+
+// Address returns the fleet address of the camera, ie. "office/Porch".
+func (c FleetCamera) Address() string {
+	return c.Server + "/" + c.Name
+}
+
+// Watch connects every server's event stream. See Events.Watch.
+func (f *Fleet) Watch(retryInterval time.Duration, refreshOnConfigChange bool) {
+	for _, member := range f.members() {
+		member.server.Events.Watch(retryInterval, refreshOnConfigChange)
+	}
+}
+
+// Stop disconnects every server's event stream, stops forwarding events and closes
+// every fleet subscription. Events are not forwarded again after Stop.
+func (f *Fleet) Stop() {
+	for _, member := range f.members() {
+		member.server.Events.Stop(false)
+		member.close()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+
+	for sub := range f.subs {
+		close(sub)
+		delete(f.subs, sub)
+	}
+}
+
+// Subscribe returns a channel that receives events from every server in the fleet,
+// tagged with the server's fleet name and UUID. Like bound channels, events are
+// dropped if the channel is full, so pick a buffer size to match your consumer.
+// After Stop, the channel is returned closed.
+func (f *Fleet) Subscribe(buffer int) <-chan FleetEvent {
+	sub := make(chan FleetEvent, buffer)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped {
+		close(sub)
+		return sub
+	}
+
+	f.subs[sub] = struct{}{}
+
+	return sub
+}
+
+// Unsubscribe stops and closes a channel returned by Subscribe.
+func (f *Fleet) Unsubscribe(sub <-chan FleetEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subs {
+		if ch == sub {
+			close(ch)
+			delete(f.subs, ch)
+		}
+	}
+}
+
+// forward copies one server's events to every subscriber until the member is closed.
+func (f *Fleet) forward(member *fleetMember, events chan Event) {
+	defer close(member.done)
+
+	for {
+		select {
+		case <-member.stop:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			fleetEvent := FleetEvent{Event: event, Server: member.name, UUID: member.server.Snapshot().Info.UUID}
+
+			f.mu.RLock()
+			for sub := range f.subs {
+				select {
+				case sub <- fleetEvent:
+				default:
+				}
+			}
+			f.mu.RUnlock()
+		}
+	}
+}
+
+// close unbinds the member's events and waits for its forward go routine to return.
+func (m *fleetMember) close() {
+	m.once.Do(func() {
+		m.binding.Unbind()
+		close(m.stop)
+		<-m.done
+	})
+}
+
+// Snapshots fetches a JPEG from every listed camera address, or every camera if none are listed.
+// Images are keyed by camera address. Returns a *FleetError keyed by camera address if any fail.
+func (f *Fleet) Snapshots(ops *VidOps, addresses ...string) (map[string]image.Image, error) {
+	var (
+		images = make(map[string]image.Image)
+		mu     sync.Mutex
+	)
+
+	err := f.eachCamera(addresses, func(camera FleetCamera) error {
+		img, err := camera.GetJPEG(ops)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		images[camera.Address()] = img
+
+		return nil
+	})
+
+	return images, err
+}
+
+// Toggle arms or disarms a mode on every listed camera address, or every camera if none are listed.
+// CameraModeAll toggles motion capture, actions and continuous capture. Servers that cannot
+// toggle continuous capture (ErrUnsupported, see Capabilities) only get the other two.
+// Returns ErrCameraMode for any other mode, or a *FleetError keyed by camera address if any fail.
+func (f *Fleet) Toggle(mode CameraMode, arm CameraArmMode, addresses ...string) error {
+	switch mode {
+	case CameraModeContinuous, CameraModeMotion, CameraModeActions, CameraModeAll:
+	default:
+		return fmt.Errorf("%w: %q", ErrCameraMode, string(mode))
+	}
+
+	return f.eachCamera(addresses, func(camera FleetCamera) error {
+		switch mode {
+		case CameraModeContinuous:
+			return camera.ToggleContinuous(arm)
+		case CameraModeMotion:
+			return camera.ToggleMotion(arm)
+		case CameraModeActions:
+			return camera.ToggleActions(arm)
+		default: // CameraModeAll.
+			if err := camera.ToggleMotion(arm); err != nil {
+				return err
+			}
+
+			if err := camera.ToggleActions(arm); err != nil {
+				return err
+			}
+
+			if err := camera.ToggleContinuous(arm); err != nil && !errors.Is(err, ErrUnsupported) {
+				return err
+			}
+
+			return nil
+		}
+	})
+}
+
+// SetSchedulePreset invokes the schedule preset with this name on every server.
+// Preset IDs differ between servers, so presets are matched by name.
+// Returns a *FleetError keyed by server name if any fail, including servers without the preset.
+func (f *Fleet) SetSchedulePreset(name string) error {
+	return f.each(context.Background(), func(_ context.Context, member *fleetMember) error {
+		for id, preset := range member.server.Snapshot().Info.SchedulePresets {
+			if preset == name {
+				return member.server.SetSchedulePreset(id)
+			}
+		}
+
+		return fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+	})
+}
+
+// eachCamera runs do for each camera address, or every camera if none are given.
+// Servers run concurrently; cameras on the same server run one at a time.
+func (f *Fleet) eachCamera(addresses []string, do func(camera FleetCamera) error) error {
+	var (
+		errs     fleetErrors
+		wg       sync.WaitGroup
+		byServer = make(map[string][]FleetCamera)
+	)
+
+	if len(addresses) == 0 {
+		for _, camera := range f.Cameras() {
+			byServer[camera.Server] = append(byServer[camera.Server], camera)
+		}
+	}
+
+	for _, address := range addresses {
+		camera, err := f.Camera(address)
+		if err != nil {
+			errs.add(address, err)
+			continue
+		}
+
+		byServer[camera.Server] = append(byServer[camera.Server], camera)
+	}
+
+	for _, cameras := range byServer {
+		wg.Go(func() {
+			for _, camera := range cameras {
+				errs.add(camera.Address(), do(camera))
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return errs.err()
+}
@@ -0,0 +1,160 @@
+package securityspy_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+func newTestFleet(t *testing.T) (*securityspy.Fleet, *securityspytest.Server, *securityspytest.Server) {
+	t.Helper()
+
+	office, home := securityspytest.NewServer(), securityspytest.NewServer()
+	t.Cleanup(office.Close)
+	t.Cleanup(home.Close)
+
+	home.SetInfo(func(info *securityspytest.ServerInfo) { info.UUID = "HOMEUUID" })
+	home.UpdateCamera(3, func(cam *securityspytest.Camera) { cam.Name = "Garage" })
+	office.AddSchedulePreset(4, "Away")
+	home.AddSchedulePreset(9, "Away")
+
+	fleet := securityspy.NewFleet()
+	require.NoError(t, fleet.Add("office", securityspy.NewMust(office.Config())))
+	require.NoError(t, fleet.Add("home", securityspy.NewMust(home.Config())))
+	require.NoError(t, fleet.Refresh(context.Background()))
+	t.Cleanup(fleet.Stop)
+
+	return fleet, office, home
+}
+
+func TestFleetAddressing(t *testing.T) {
+	t.Parallel()
+
+	fleet, _, _ := newTestFleet(t)
+
+	require.ErrorIs(t, fleet.Add("office", fleet.Server("home")), securityspy.ErrFleetServerExists)
+	require.ErrorIs(t, fleet.Add("a/b", nil), securityspy.ErrFleetServerName)
+	require.Equal(t, []string{"office", "home"}, fleet.Names())
+
+	camera, err := fleet.Camera("home/Garage")
+	require.NoError(t, err)
+	require.Equal(t, 3, camera.Number)
+	require.Equal(t, "HOMEUUID", camera.UUID)
+	require.Equal(t, "home/Garage", camera.Address())
+
+	camera, err = fleet.Camera("office/3")
+	require.NoError(t, err)
+	require.Equal(t, "Door", camera.Name)
+
+	camera, err = fleet.Camera("HOMEUUID/2")
+	require.NoError(t, err, "servers can be addressed by UUID")
+	require.Equal(t, "home", camera.Server)
+
+	_, err = fleet.Camera("nowhere/1")
+	require.ErrorIs(t, err, securityspy.ErrFleetServerNotFound)
+	_, err = fleet.Camera("office/99")
+	require.ErrorIs(t, err, securityspy.ErrCameraNotFound)
+
+	require.Len(t, fleet.Cameras(), 4)
+	require.False(t, fleet.Server("home").Poller.Health().LastSuccess.IsZero(), "fleet refreshes go through the Poller")
+	require.Equal(t, "office/Porch", fleet.Cameras()[0].Address())
+}
+
+func TestFleetBulkOperations(t *testing.T) {
+	t.Parallel()
+
+	fleet, office, home := newTestFleet(t)
+
+	require.NoError(t, fleet.Toggle(securityspy.CameraModeContinuous, securityspy.CameraArm, "office/Door", "home/Garage"))
+
+	officeDoor, _ := office.Camera(3)
+	homeGarage, _ := home.Camera(3)
+	require.True(t, officeDoor.ModeC)
+	require.True(t, homeGarage.ModeC)
+
+	require.NoError(t, fleet.SetSchedulePreset("Away"))
+	require.Equal(t, 4, office.ActivePreset())
+	require.Equal(t, 9, home.ActivePreset())
+
+	err := fleet.SetSchedulePreset("Vacation")
+	require.ErrorIs(t, err, securityspy.ErrPresetNotFound)
+
+	var fleetErr *securityspy.FleetError
+	require.ErrorAs(t, err, &fleetErr)
+	require.Len(t, fleetErr.Errors, 2)
+
+	home.Handle("/++ssControlContinuous", http.NotFound) // Like 6.20.
+	require.NoError(t, fleet.Toggle(securityspy.CameraModeAll, securityspy.CameraDisarm, "office/Door", "home/Garage"),
+		"servers without continuous control still toggle motion and actions")
+
+	homeGarage, _ = home.Camera(3)
+	require.False(t, homeGarage.ModeM)
+	require.False(t, homeGarage.ModeA)
+	require.ErrorIs(t, fleet.Toggle(securityspy.CameraMode('Q'), securityspy.CameraArm), securityspy.ErrCameraMode)
+
+	home.Handle("/++image", func(resp http.ResponseWriter, _ *http.Request) {
+		http.Error(resp, "busy", http.StatusInternalServerError)
+	})
+
+	images, err := fleet.Snapshots(nil, "office/Door", "home/Garage", "home/Nope")
+	require.ErrorAs(t, err, &fleetErr)
+	require.Len(t, images, 1)
+	require.NotNil(t, images["office/Door"])
+	require.Contains(t, fleetErr.Errors, "home/Garage")
+	require.ErrorIs(t, fleetErr.Errors["home/Nope"], securityspy.ErrCameraNotFound)
+}
+
+func TestFleetEvents(t *testing.T) {
+	t.Parallel()
+
+	fleet, office, home := newTestFleet(t)
+	events := fleet.Subscribe(10)
+
+	fleet.Watch(10*time.Millisecond, false)
+	require.Eventually(t, func() bool {
+		return office.EventStreamClients() == 1 && home.EventStreamClients() == 1
+	}, time.Second, time.Millisecond)
+
+	home.PushEvent(3, "TRIGGER_M", "1")
+
+	for event := range events {
+		if event.Type != securityspy.EventTriggerMotion {
+			continue
+		}
+
+		require.Equal(t, "home", event.Server)
+		require.Equal(t, "HOMEUUID", event.UUID)
+		require.Equal(t, "Garage", event.Camera.Name)
+
+		break
+	}
+
+	fleet.Unsubscribe(events)
+
+	_, ok := <-events
+	require.False(t, ok, "unsubscribe closes the channel")
+}
+
+func TestFleetRemoveAndStop(t *testing.T) {
+	t.Parallel()
+
+	fleet, _, _ := newTestFleet(t)
+	office, home := fleet.Server("office"), fleet.Server("home")
+	require.Len(t, office.Events.BindingStats(), 1)
+
+	fleet.Remove("office")
+	require.Empty(t, office.Events.BindingStats(), "Remove unbinds the server's events")
+	require.Equal(t, []string{"home"}, fleet.Names())
+
+	fleet.Stop()
+	require.Empty(t, home.Events.BindingStats(), "Stop unbinds every server's events")
+	fleet.Stop()
+
+	_, ok := <-fleet.Subscribe(1)
+	require.False(t, ok, "subscriptions after Stop are closed")
+}
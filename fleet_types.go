@@ -0,0 +1,132 @@
+package securityspy
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// These errors are returned by Fleet methods.
+var (
+	// ErrFleetServerExists is returned when adding a server with a name already in the fleet.
+	ErrFleetServerExists = errors.New("fleet server name already in use")
+	// ErrFleetServerName is returned when adding a server with an empty name or a name containing a slash.
+	ErrFleetServerName = errors.New("invalid fleet server name")
+	// ErrFleetServerNotFound is returned when a fleet address names a server that is not in the fleet.
+	ErrFleetServerNotFound = errors.New("fleet server not found")
+	// ErrCameraNotFound is returned when a fleet address names a camera that does not exist.
+	ErrCameraNotFound = errors.New("camera not found")
+	// ErrPresetNotFound is returned when a schedule preset name does not exist on a server.
+	ErrPresetNotFound = errors.New("schedule preset not found")
+	// ErrCameraMode is returned by Fleet.Toggle for a mode that is not one of the CameraMode constants.
+	ErrCameraMode = errors.New("unknown camera mode")
+)
+
+// FleetEventBuffer is the channel buffer size for each server's events inside a Fleet.
+const FleetEventBuffer = 1000
+
+// Fleet manages several SecuritySpy servers as one. Each server has a fleet name,
+// and cameras are addressed as "server/camera", where camera is a name or a number,
+// ie. "office/Porch" or "office/3". The server part may also be the server's UUID.
+type Fleet struct {
+	mu      sync.RWMutex
+	servers map[string]*fleetMember
+	order   []string
+	subs    map[chan FleetEvent]struct{}
+	stopped bool // Stop was called; new subscriptions are closed right away.
+}
+
+type fleetMember struct {
+	name    string
+	server  *Server
+	binding *Binding // Sends the server's events to forward.
+	stop    chan struct{}
+	done    chan struct{} // Closed when forward returns.
+	once    sync.Once
+}
+
+// FleetCamera is a camera and the fleet server it belongs to.
+type FleetCamera struct {
+	*Camera
+
+	Server string // Fleet name of the server.
+	UUID   string // UUID of the server.
+}
+
+// FleetEvent is an event from one of the servers in a fleet.
+type FleetEvent struct {
+	Event
+
+	Server string // Fleet name of the server the event came from.
+	UUID   string // UUID of the server the event came from.
+}
+
+// FleetError holds the errors from a bulk Fleet operation, keyed by fleet
+// server name, or by camera address for per-camera operations.
+type FleetError struct {
+	Errors map[string]error
+}
+
+// Error lists every failure, sorted by key.
+func (e *FleetError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, key := range slices.Sorted(maps.Keys(e.Errors)) {
+		msgs = append(msgs, key+": "+e.Errors[key].Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns every error, so errors.Is and errors.As match any of them.
+func (e *FleetError) Unwrap() []error {
+	return slices.Collect(maps.Values(e.Errors))
+}
+
+// fleetErrors collects errors from concurrent fleet operations.
+type fleetErrors struct {
+	mu     sync.Mutex
+	errors map[string]error
+}
+
+func (f *fleetErrors) add(key string, err error) {
+	if err == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.errors == nil {
+		f.errors = make(map[string]error)
+	}
+
+	f.errors[key] = err
+}
+
+// err returns nil if nothing failed, or a *FleetError.
+func (f *fleetErrors) err() error {
+	if len(f.errors) == 0 {
+		return nil
+	}
+
+	return &FleetError{Errors: f.errors}
+}
+
+// each runs do for every member concurrently and waits for them.
+func (f *Fleet) each(ctx context.Context, do func(ctx context.Context, member *fleetMember) error) error {
+	var (
+		errs fleetErrors
+		wg   sync.WaitGroup
+	)
+
+	for _, member := range f.members() {
+		wg.Go(func() { errs.add(member.name, do(ctx, member)) })
+	}
+
+	wg.Wait()
+
+	return errs.err()
+}
@@ -0,0 +1,271 @@
+package forwarder
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/server"
+)
+
+// Signature headers sent with each POST when the destination has a Secret.
+const (
+	SignatureHeader = "X-Securityspy-Signature" // sha256= and the hex signature from Sign.
+	TimestampHeader = "X-Securityspy-Timestamp" // Unix seconds when the POST was signed.
+)
+
+// Start subscribes each destination to the server's events and forwards them until
+// ctx ends or Stop is called. Spooled events from an earlier run are sent first.
+// Events that arrive before Start are not forwarded, so call it before Events.Watch.
+func (f *Forwarder) Start(ctx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.cancel != nil {
+		return
+	}
+
+	ctx, f.cancel = context.WithCancel(ctx)
+
+	source := f.config.Source
+	if source == "" {
+		source = "urn:securityspy:" + f.server.Snapshot().Info.UUID
+	}
+
+	for _, dest := range f.dests {
+		sub := f.server.Events.Subscribe(ctx, dest.Filter)
+		f.wg.Go(func() { dest.run(ctx, sub, source) })
+	}
+}
+
+// Stop stops forwarding and waits for deliveries in progress to finish or be spooled.
+// Events still waiting in the subscriptions are discarded.
+func (f *Forwarder) Stop() {
+	f.mu.Lock()
+	cancel := f.cancel
+	f.cancel = nil
+	f.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		f.wg.Wait()
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of timestamp, a period, and body, keyed with secret.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers on a POST from a Forwarder. Receivers should
+// also reject old timestamps, so a captured request cannot be replayed later.
+func Verify(secret []byte, header http.Header, body []byte) bool {
+	signature, ok := strings.CutPrefix(header.Get(SignatureHeader), "sha256=")
+	if !ok {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, header.Get(TimestampHeader), body)))
+}
+
+// run forwards events from the subscription, and retries the spool, until ctx ends.
+func (d *destination) run(ctx context.Context, sub <-chan securityspy.Event, source string) {
+	ticker := time.NewTicker(d.fwd.config.SpoolRetry)
+	defer ticker.Stop()
+
+	d.flush(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.flush(ctx)
+		case event, ok := <-sub:
+			if !ok {
+				return
+			}
+
+			if event.Type == securityspy.EventKeepAlive && len(d.Filter.Types) == 0 {
+				continue
+			}
+
+			body, err := d.encode(&event, source)
+			if err != nil {
+				d.update(err, func(stats *Stats) { stats.Failed++ })
+				continue
+			}
+
+			d.forward(ctx, body)
+		}
+	}
+}
+
+// forward sends an event, or spools it if it cannot be sent. While the spool has
+// events, new ones go to the end of it, so the destination gets them in order.
+// A new event also flushes the spool if it was last tried more than RetryDelay
+// ago, so a destination that comes back gets events again without waiting for SpoolRetry.
+func (d *destination) forward(ctx context.Context, body []byte) {
+	if d.pending() > 0 {
+		d.save(body)
+
+		if time.Since(d.flushed) >= d.fwd.config.RetryDelay {
+			d.flush(ctx)
+		}
+
+		return
+	}
+
+	switch err := d.send(ctx, body, d.fwd.config.MaxAttempts); {
+	case err == nil:
+		d.update(nil, func(stats *Stats) { stats.Sent++ })
+	case errors.Is(err, ErrRejected) || d.spool == "":
+		d.update(err, func(stats *Stats) { stats.Failed++ })
+	default:
+		d.update(err, nil)
+		d.save(body)
+	}
+}
+
+// send POSTs a body until it is delivered, rejected, ctx ends, or it was tried attempts times.
+func (d *destination) send(ctx context.Context, body []byte, attempts int) error {
+	policy := server.RetryPolicy{
+		BaseDelay: server.Duration{Duration: d.fwd.config.RetryDelay},
+		MaxDelay:  server.Duration{Duration: d.fwd.config.MaxRetryDelay},
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := d.post(ctx, body)
+		if err == nil || errors.Is(err, ErrRejected) || attempt >= attempts || ctx.Err() != nil {
+			return err
+		}
+
+		d.update(err, func(stats *Stats) { stats.Retries++ })
+
+		timer := time.NewTimer(policy.Delay(attempt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// post makes one delivery attempt.
+func (d *destination) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrDelivery, d.Name, err)
+	}
+
+	for key, values := range d.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	req.Header.Set("Content-Type", ContentType)
+
+	if len(d.Secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(d.Secret, timestamp, body))
+	}
+
+	resp, err := d.fwd.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrDelivery, d.Name, err)
+	}
+	defer resp.Body.Close()
+
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, server.MaxErrorBody))
+
+	switch code := resp.StatusCode; {
+	case code >= http.StatusOK && code < http.StatusMultipleChoices:
+		return nil
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %s: status %d (%q)", ErrDelivery, d.Name, code, reply)
+	default:
+		return fmt.Errorf("%w: %s: status %d (%q)", ErrRejected, d.Name, code, reply)
+	}
+}
+
+// encode makes the CloudEvent body for an event, with a snapshot if the destination wants one.
+func (d *destination) encode(event *securityspy.Event, source string) ([]byte, error) {
+	cloud := CloudEvent{
+		SpecVersion:     SpecVersion,
+		ID:              rand.Text(),
+		Source:          source,
+		Type:            d.fwd.config.TypePrefix + string(event.Type),
+		Time:            event.When,
+		DataContentType: "application/json",
+		Data:            Data{Event: event.Record()},
+	}
+
+	if event.Camera != nil {
+		cloud.Subject = event.Camera.Name
+	}
+
+	if event.Camera != nil && d.Snapshot {
+		var err error
+		if cloud.Data.Snapshot, err = d.fwd.snapshot(event.Camera); err != nil {
+			cloud.Data.SnapshotError = err.Error()
+		}
+	}
+
+	body, err := json.Marshal(cloud)
+	if err != nil {
+		return nil, fmt.Errorf("encoding event: %w", err)
+	}
+
+	return body, nil
+}
+
+// snapshot gets a JPEG from a camera, unchanged from how the server sent it.
+func (f *Forwarder) snapshot(camera *securityspy.Camera) ([]byte, error) {
+	var ops *securityspy.VidOps
+
+	if f.config.SnapshotOps != nil {
+		copied := *f.config.SnapshotOps // GetJPEGBytes changes its input.
+		ops = &copied
+	}
+
+	data, err := camera.GetJPEGBytes(ops)
+	if err != nil {
+		return nil, fmt.Errorf("getting snapshot: %w", err)
+	}
+
+	return data, nil
+}
+
+// update changes the stats with the lock held, and records err if it is not nil. change may be nil.
+func (d *destination) update(err error, change func(stats *Stats)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if change != nil {
+		change(&d.stats)
+	}
+
+	if err != nil {
+		d.stats.LastError, d.stats.LastErrorAt = err, time.Now()
+	}
+}
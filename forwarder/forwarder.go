@@ -0,0 +1,245 @@
+// Package forwarder POSTs SecuritySpy events to webhooks as CloudEvents.
+// Each destination has its own Events subscription and filter, so a slow or
+// down destination does not hold up the others. Failed deliveries are retried
+// with backoff, then spooled to disk and sent again once the destination is back.
+//
+//	fwd, err := forwarder.New(sspy, &forwarder.Config{
+//		SpoolDir: "/var/spool/myapp",
+//		Destinations: []forwarder.Destination{{
+//			Name:   "alarm",
+//			URL:    "https://alarm.internal/hooks/securityspy",
+//			Secret: []byte("shared secret"),
+//			Filter: securityspy.Filter{Types: []securityspy.EventType{securityspy.EventTriggerMotion}},
+//		}},
+//	})
+//	fwd.Start(ctx)
+//	sspy.Events.Watch(time.Second*10, true)
+package forwarder
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golift.io/securityspy/v2"
+)
+
+const (
+	// DefaultTypePrefix is prepended to the event type to make the CloudEvents type.
+	DefaultTypePrefix = "io.golift.securityspy."
+	// DefaultTimeout limits each POST when Config.Client is nil.
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxAttempts is the number of tries for each event before it is spooled.
+	DefaultMaxAttempts = 5
+	// DefaultRetryDelay is the delay before the first retry. It doubles for each retry after that.
+	DefaultRetryDelay = time.Second
+	// DefaultMaxRetryDelay caps the delay between retries.
+	DefaultMaxRetryDelay = 30 * time.Second
+	// DefaultSpoolRetry is how often spooled events are sent again.
+	DefaultSpoolRetry = 30 * time.Second
+	// DefaultSpoolLimit is the number of events each destination may spool.
+	DefaultSpoolLimit = 10000
+	// ContentType is the content type of every POST: a structured-mode CloudEvent.
+	ContentType = "application/cloudevents+json; charset=utf-8"
+	// SpecVersion is the CloudEvents version this package sends.
+	SpecVersion = "1.0"
+)
+
+// Errors returned by New and kept in Stats.LastError.
+var (
+	ErrDestination = errors.New("invalid destination")
+	ErrDelivery    = errors.New("event delivery failed")
+	// ErrRejected means the destination answered with a 4xx status other than 408 or 429.
+	// Rejected events are not retried or spooled.
+	ErrRejected = errors.New("destination rejected event")
+)
+
+// Config is the input for New.
+type Config struct {
+	Destinations []Destination
+	// Source is the CloudEvents source. Defaults to urn:securityspy: and the server's UUID.
+	Source string
+	// TypePrefix is prepended to the event type, ie. TRIGGER_M, to make the CloudEvents type.
+	// Defaults to DefaultTypePrefix.
+	TypePrefix string
+	// Client sends the POSTs. Defaults to a client with DefaultTimeout.
+	Client *http.Client
+	// MaxAttempts is the number of tries for each event. 0 uses DefaultMaxAttempts.
+	MaxAttempts int
+	// RetryDelay is the delay before the first retry. It doubles for each retry, up to MaxRetryDelay.
+	// 0 uses DefaultRetryDelay and DefaultMaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// SpoolDir holds events that could not be delivered, in one directory per destination.
+	// Empty disables the spool, and undelivered events are dropped.
+	SpoolDir string
+	// SpoolLimit is the number of events each destination may spool. The oldest are
+	// removed to make room. 0 uses DefaultSpoolLimit.
+	SpoolLimit int
+	// SpoolRetry is how often spooled events are sent again. 0 uses DefaultSpoolRetry.
+	SpoolRetry time.Duration
+	// SnapshotOps sets the size of snapshot attachments. Nil uses the camera's defaults.
+	SnapshotOps *securityspy.VidOps
+}
+
+// Destination is a webhook URL and the events it receives.
+type Destination struct {
+	// Name identifies the destination in Stats and names its spool directory. Required and unique.
+	Name string
+	URL  string
+	// Filter picks the events this destination receives. With no Types set,
+	// keep-alive (NULL) events are left out.
+	Filter securityspy.Filter
+	// Secret signs each POST with HMAC-SHA256. See Sign and Verify. Empty sends no signature.
+	Secret []byte
+	// Header is added to each POST, ie. for an Authorization token.
+	Header http.Header
+	// Snapshot attaches a JPEG from the event's camera, fetched with Camera.GetJPEGBytes.
+	Snapshot bool
+}
+
+// Stats are the delivery counters for one destination.
+type Stats struct {
+	Destination string
+	Sent        int // Events delivered, including spooled events.
+	Retries     int // Failed attempts that were tried again.
+	Failed      int // Events rejected, or dropped with no spool or a full one.
+	Spooled     int // Events written to the spool.
+	Pending     int // Events in the spool now.
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// CloudEvent is the JSON body of each POST.
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"` // Camera name.
+	Time            time.Time `json:"time"`              // Event time according to the server.
+	DataContentType string    `json:"datacontenttype"`
+	Data            Data      `json:"data"`
+}
+
+// Data is the data of each CloudEvent.
+type Data struct {
+	Event         securityspy.EventRecord `json:"event"`
+	Snapshot      []byte                  `json:"snapshot,omitempty"` // JPEG, base64 encoded in JSON.
+	SnapshotError string                  `json:"snapshotError,omitempty"`
+}
+
+// Forwarder sends events from one server to webhooks. Create one with New.
+type Forwarder struct {
+	server *securityspy.Server
+	config Config
+	dests  []*destination
+	mu     sync.Mutex
+	cancel func()
+	wg     sync.WaitGroup
+}
+
+// destination is a Destination and its delivery state.
+type destination struct {
+	Destination
+	fwd     *Forwarder
+	spool   string     // Spool directory. Empty without Config.SpoolDir.
+	flushed time.Time  // Last time the spool was tried. Only run's go routine uses this.
+	mu      sync.Mutex // Protects stats.
+	stats   Stats
+}
+
+// New checks the destinations, creates their spool directories and returns a Forwarder.
+// Call Start to begin forwarding.
+func New(server *securityspy.Server, config *Config) (*Forwarder, error) {
+	fwd := &Forwarder{server: server, config: *config}
+	fwd.setDefaults()
+
+	names := []string{}
+
+	for _, dest := range config.Destinations {
+		if err := checkDestination(&dest, names); err != nil {
+			return nil, err
+		}
+
+		names = append(names, dest.Name)
+		fwdDest := &destination{Destination: dest, fwd: fwd, stats: Stats{Destination: dest.Name}}
+
+		if fwd.config.SpoolDir != "" {
+			fwdDest.spool = filepath.Join(fwd.config.SpoolDir, dest.Name)
+			if err := os.MkdirAll(fwdDest.spool, 0o750); err != nil { //nolint:mnd
+				return nil, fmt.Errorf("creating spool directory: %w", err)
+			}
+		}
+
+		fwd.dests = append(fwd.dests, fwdDest)
+	}
+
+	return fwd, nil
+}
+
+func (f *Forwarder) setDefaults() {
+	if f.config.TypePrefix == "" {
+		f.config.TypePrefix = DefaultTypePrefix
+	}
+
+	if f.config.Client == nil {
+		f.config.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	if f.config.MaxAttempts <= 0 {
+		f.config.MaxAttempts = DefaultMaxAttempts
+	}
+
+	if f.config.RetryDelay <= 0 {
+		f.config.RetryDelay = DefaultRetryDelay
+	}
+
+	if f.config.MaxRetryDelay <= 0 {
+		f.config.MaxRetryDelay = DefaultMaxRetryDelay
+	}
+
+	if f.config.SpoolLimit <= 0 {
+		f.config.SpoolLimit = DefaultSpoolLimit
+	}
+
+	if f.config.SpoolRetry <= 0 {
+		f.config.SpoolRetry = DefaultSpoolRetry
+	}
+}
+
+func checkDestination(dest *Destination, names []string) error {
+	switch {
+	case dest.Name == "" || strings.ContainsAny(dest.Name, `/\`) || dest.Name == "." || dest.Name == "..":
+		return fmt.Errorf("%w: name %q must be a valid directory name", ErrDestination, dest.Name)
+	case slices.Contains(names, dest.Name):
+		return fmt.Errorf("%w: duplicate name %q", ErrDestination, dest.Name)
+	}
+
+	parsed, err := url.Parse(dest.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: %s: bad url %q", ErrDestination, dest.Name, dest.URL)
+	}
+
+	return nil
+}
+
+// Stats returns the delivery counters for every destination, in Config order.
+func (f *Forwarder) Stats() []Stats {
+	stats := make([]Stats, 0, len(f.dests))
+
+	for _, dest := range f.dests {
+		dest.mu.Lock()
+		stats = append(stats, dest.stats)
+		dest.mu.Unlock()
+	}
+
+	return stats
+}
@@ -0,0 +1,243 @@
+package forwarder_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/forwarder"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+// receiver is a webhook that records what it is sent, and answers with status.
+type receiver struct {
+	status  atomic.Int32
+	mu      sync.Mutex
+	events  []forwarder.CloudEvent
+	headers []http.Header
+	bodies  [][]byte
+}
+
+func newReceiver(t *testing.T) (*receiver, string) {
+	t.Helper()
+
+	recv := &receiver{}
+	recv.status.Store(http.StatusOK)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		status := int(recv.status.Load())
+		if status == http.StatusOK {
+			var event forwarder.CloudEvent
+			if json.Unmarshal(body, &event) == nil {
+				recv.mu.Lock()
+				recv.events = append(recv.events, event)
+				recv.headers = append(recv.headers, req.Header)
+				recv.bodies = append(recv.bodies, body)
+				recv.mu.Unlock()
+			}
+		}
+
+		resp.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	return recv, srv.URL
+}
+
+func (r *receiver) received() []forwarder.CloudEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]forwarder.CloudEvent{}, r.events...)
+}
+
+// start connects to a fake server, starts fwd and watches the event stream.
+func start(t *testing.T, config *forwarder.Config) (*securityspytest.Server, *forwarder.Forwarder) {
+	t.Helper()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	fwd, err := forwarder.New(sspy, config)
+	require.NoError(t, err)
+
+	fwd.Start(context.Background())
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() {
+		sspy.Events.Stop(false)
+		fwd.Stop()
+	})
+
+	require.Eventually(t, func() bool { return fake.EventStreamClients() == 1 }, time.Second, time.Millisecond)
+
+	return fake, fwd
+}
+
+func TestForwarder(t *testing.T) {
+	t.Parallel()
+
+	door, doorURL := newReceiver(t)
+	porch, porchURL := newReceiver(t)
+	secret := []byte("shh")
+
+	fake, fwd := start(t, &forwarder.Config{Destinations: []forwarder.Destination{{
+		Name:     "door",
+		URL:      doorURL,
+		Filter:   securityspy.Filter{Cameras: []int{3}},
+		Header:   http.Header{"Authorization": {"Bearer token"}},
+		Snapshot: true,
+	}, {
+		Name:   "porch",
+		URL:    porchURL,
+		Secret: secret,
+		Filter: securityspy.Filter{CameraNames: []string{"Porch"}},
+	}}})
+
+	var served bytes.Buffer
+	require.NoError(t, jpeg.Encode(&served, image.NewGray(image.Rect(0, 0, 4, 4)), &jpeg.Options{Quality: 90}))
+	fake.SetJPEG(served.Bytes())
+	fake.PushEvent(3, "TRIGGER_M", "129")
+	fake.PushEvent(2, "OFFLINE")
+
+	require.Eventually(t, func() bool { return len(door.received()) == 1 && len(porch.received()) == 1 },
+		2*time.Second, time.Millisecond)
+
+	event := door.received()[0]
+	require.Equal(t, forwarder.SpecVersion, event.SpecVersion)
+	require.Equal(t, "io.golift.securityspy.TRIGGER_M", event.Type)
+	require.Equal(t, "Door", event.Subject)
+	require.NotEmpty(t, event.ID)
+	require.Contains(t, event.Source, "urn:securityspy:")
+	require.Equal(t, 3, event.Data.Event.Camera)
+	require.Contains(t, event.Data.Event.Reasons, securityspy.TriggerByHumanDetection)
+	require.Empty(t, event.Data.SnapshotError)
+	require.Equal(t, served.Bytes(), event.Data.Snapshot, "the snapshot is attached as the server sent it")
+	require.Equal(t, forwarder.ContentType, door.headers[0].Get("Content-Type"))
+	require.Equal(t, "Bearer token", door.headers[0].Get("Authorization"))
+	require.Empty(t, door.headers[0].Get(forwarder.SignatureHeader))
+
+	event = porch.received()[0]
+	require.Equal(t, "io.golift.securityspy.OFFLINE", event.Type)
+	require.Empty(t, event.Data.Snapshot)
+	require.True(t, forwarder.Verify(secret, porch.headers[0], porch.bodies[0]))
+	require.False(t, forwarder.Verify([]byte("wrong"), porch.headers[0], porch.bodies[0]))
+
+	require.Eventually(t, func() bool { return fwd.Stats()[0].Sent == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, "porch", fwd.Stats()[1].Destination)
+}
+
+func TestForwarderRetryAndSpool(t *testing.T) {
+	t.Parallel()
+
+	recv, url := newReceiver(t)
+	fake, fwd := start(t, &forwarder.Config{
+		Destinations: []forwarder.Destination{{
+			Name:   "flaky",
+			URL:    url,
+			Filter: securityspy.Filter{Types: []securityspy.EventType{securityspy.EventOnline}},
+		}},
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+		SpoolDir:    t.TempDir(),
+		SpoolRetry:  20 * time.Millisecond,
+	})
+	stats := func() forwarder.Stats { return fwd.Stats()[0] }
+
+	recv.status.Store(http.StatusServiceUnavailable)
+	fake.PushEvent(3, "ONLINE")
+	require.Eventually(t, func() bool { return stats().Pending == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, 2, stats().Retries, "three attempts, then the spool")
+	require.ErrorIs(t, stats().LastError, forwarder.ErrDelivery)
+
+	fake.PushEvent(2, "ONLINE")
+	require.Eventually(t, func() bool { return stats().Pending == 2 }, time.Second, time.Millisecond)
+	require.Equal(t, 2, stats().Retries, "events go straight to a spool that is not empty")
+
+	recv.status.Store(http.StatusOK)
+	require.Eventually(t, func() bool { return stats().Sent == 2 }, time.Second, time.Millisecond)
+	require.Zero(t, stats().Pending)
+
+	events := recv.received()
+	require.Equal(t, 3, events[0].Data.Event.Camera, "spooled events are sent in order")
+	require.Equal(t, 2, events[1].Data.Event.Camera)
+
+	recv.status.Store(http.StatusBadRequest)
+	fake.PushEvent(3, "ONLINE")
+	require.Eventually(t, func() bool { return stats().Failed == 1 }, time.Second, time.Millisecond)
+	require.ErrorIs(t, stats().LastError, forwarder.ErrRejected)
+	require.Zero(t, stats().Pending, "rejected events are not spooled")
+	require.Equal(t, 2, stats().Spooled)
+}
+
+func TestForwarderSpoolRecovers(t *testing.T) {
+	t.Parallel()
+
+	recv, url := newReceiver(t)
+	fake, fwd := start(t, &forwarder.Config{
+		Destinations: []forwarder.Destination{{
+			Name:   "flaky",
+			URL:    url,
+			Filter: securityspy.Filter{Types: []securityspy.EventType{securityspy.EventOnline}},
+		}},
+		MaxAttempts: 1,
+		RetryDelay:  time.Millisecond,
+		SpoolDir:    t.TempDir(),
+		SpoolRetry:  time.Hour,
+	})
+	stats := func() forwarder.Stats { return fwd.Stats()[0] }
+
+	recv.status.Store(http.StatusServiceUnavailable)
+	fake.PushEvent(3, "ONLINE")
+	require.Eventually(t, func() bool { return stats().Pending == 1 }, time.Second, time.Millisecond)
+
+	recv.status.Store(http.StatusOK)
+	time.Sleep(5 * time.Millisecond) // Longer than RetryDelay.
+	fake.PushEvent(2, "ONLINE")
+	require.Eventually(t, func() bool { return stats().Sent == 2 }, time.Second, time.Millisecond,
+		"a new event flushes the spool long before SpoolRetry")
+	require.Zero(t, stats().Pending)
+
+	fake.PushEvent(3, "ONLINE")
+	require.Eventually(t, func() bool { return stats().Sent == 3 }, time.Second, time.Millisecond)
+	require.Equal(t, 2, stats().Spooled, "events go straight out once the spool is empty")
+
+	events := recv.received()
+	require.Equal(t, 3, events[0].Data.Event.Camera)
+	require.Equal(t, 2, events[1].Data.Event.Camera)
+	require.Equal(t, 3, events[2].Data.Event.Camera)
+}
+
+func TestNewBadDestination(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy := securityspy.NewMust(fake.Config())
+
+	for _, dests := range [][]forwarder.Destination{
+		{{Name: "", URL: "http://localhost"}},
+		{{Name: "../up", URL: "http://localhost"}},
+		{{Name: "a", URL: "ftp://localhost"}},
+		{{Name: "a", URL: "http://localhost"}, {Name: "a", URL: "http://localhost"}},
+	} {
+		_, err := forwarder.New(sspy, &forwarder.Config{Destinations: dests})
+		require.ErrorIs(t, err, forwarder.ErrDestination)
+	}
+}
@@ -0,0 +1,145 @@
+package forwarder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// spoolExt is the file extension of spooled events. Files being written end in .tmp.
+const spoolExt = ".json"
+
+// pending returns the number of spooled events.
+func (d *destination) pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.stats.Pending
+}
+
+// spooled lists the spooled event files, oldest first.
+func (d *destination) spooled() ([]string, error) {
+	entries, err := os.ReadDir(d.spool)
+	if err != nil {
+		return nil, fmt.Errorf("reading spool: %w", err)
+	}
+
+	names := []string{}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), spoolExt) {
+			names = append(names, entry.Name()) // ReadDir sorts by name, and names are times.
+		}
+	}
+
+	return names, nil
+}
+
+// save adds an event to the end of the spool, removing the oldest ones if it is full.
+// Without a spool the event is dropped.
+func (d *destination) save(body []byte) {
+	if d.spool == "" {
+		d.update(nil, func(stats *Stats) { stats.Failed++ })
+		return
+	}
+
+	if d.pending() >= d.fwd.config.SpoolLimit {
+		d.trim()
+	}
+
+	// Names are the time in nanoseconds, so they sort by age. Only this
+	// destination's goroutine writes here, so a free name stays free.
+	name := ""
+	for now := time.Now().UnixNano(); ; now++ {
+		name = fmt.Sprintf("%020d%s", now, spoolExt)
+		if _, err := os.Lstat(filepath.Join(d.spool, name)); err != nil {
+			break
+		}
+	}
+
+	path := filepath.Join(d.spool, name)
+	if err := os.WriteFile(path+".tmp", body, 0o600); err != nil { //nolint:mnd
+		d.update(fmt.Errorf("spooling event: %w", err), func(stats *Stats) { stats.Failed++ })
+		return
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = os.Remove(path + ".tmp")
+		d.update(fmt.Errorf("spooling event: %w", err), func(stats *Stats) { stats.Failed++ })
+
+		return
+	}
+
+	d.update(nil, func(stats *Stats) {
+		stats.Spooled++
+		stats.Pending++
+	})
+}
+
+// trim removes the oldest spooled events until there is room for one more.
+func (d *destination) trim() {
+	names, err := d.spooled()
+	if err != nil {
+		d.update(err, nil)
+		return
+	}
+
+	for len(names) >= d.fwd.config.SpoolLimit {
+		_ = os.Remove(filepath.Join(d.spool, names[0]))
+		names = names[1:]
+
+		d.update(nil, func(stats *Stats) { stats.Failed++ })
+	}
+
+	d.update(nil, func(stats *Stats) { stats.Pending = len(names) })
+}
+
+// flush sends spooled events, oldest first, and stops at the first one that fails.
+// Each gets one attempt; the next flush tries again.
+func (d *destination) flush(ctx context.Context) {
+	if d.spool == "" {
+		return
+	}
+
+	d.flushed = time.Now()
+
+	names, err := d.spooled()
+	if err != nil {
+		d.update(err, nil)
+		return
+	}
+
+	d.update(nil, func(stats *Stats) { stats.Pending = len(names) })
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return
+		}
+
+		path := filepath.Join(d.spool, name)
+
+		body, err := os.ReadFile(path)
+		if err != nil {
+			err = fmt.Errorf("reading spool: %w", err)
+		} else if err = d.send(ctx, body, 1); err != nil && !errors.Is(err, ErrRejected) {
+			d.update(err, nil)
+			return
+		}
+
+		_ = os.Remove(path) // Delivered, rejected or unreadable.
+
+		d.update(err, func(stats *Stats) {
+			stats.Pending--
+
+			if err == nil {
+				stats.Sent++
+			} else {
+				stats.Failed++
+			}
+		})
+	}
+}
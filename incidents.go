@@ -0,0 +1,262 @@
+package securityspy
+
+import (
+	"slices"
+	"time"
+)
+
+// NewIncidentTracker binds a tracker to every event from events and starts it. Config may
+// be nil. Bind callbacks or channels to receive completed incidents, then call Events.Watch.
+// Call Stop when finished. Timeouts follow the Time of server events: each one moves the
+// tracker's clock to its Time, and the clock runs on with the wall clock until the next.
+// Live events are stamped with the local time, so this is the wall clock. Replayed events
+// carry their recorded time, so a replay splits incidents the same way at every speed.
+func NewIncidentTracker(events *Events, config *IncidentConfig) *IncidentTracker {
+	if config == nil {
+		config = &IncidentConfig{}
+	}
+
+	tracker := &IncidentTracker{
+		events: events,
+		config: *config,
+		input:  make(chan Event, IncidentBuffer),
+		stop:   make(chan struct{}),
+		open:   make(map[int]*Incident),
+	}
+
+	if tracker.config.IdleTimeout <= 0 {
+		tracker.config.IdleTimeout = DefaultIncidentIdle
+	}
+
+	if tracker.config.FileWait <= 0 {
+		tracker.config.FileWait = DefaultIncidentFileWait
+	}
+
+	if tracker.config.MaxDuration <= 0 {
+		tracker.config.MaxDuration = DefaultIncidentMaxDuration
+	}
+
+	tracker.binding = events.BindChanConfig(EventAllEvents, tracker.input, nil)
+	tracker.wg.Go(tracker.run)
+
+	return tracker
+}
+
+// BindFunc calls a function in a go routine with every completed incident. Panics are
+// recovered and reported with EventCallbackPanic, for the incident's last event.
+func (t *IncidentTracker) BindFunc(callBack func(Incident)) {
+	if callBack == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.funcs = append(t.funcs, callBack)
+}
+
+// BindChan sends every completed incident to a channel. Like bound event channels,
+// incidents are dropped if the channel is full.
+func (t *IncidentTracker) BindChan(channel chan Incident) {
+	if channel == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.chans = append(t.chans, channel)
+}
+
+// Open returns the incidents that are still in progress.
+func (t *IncidentTracker) Open() []Incident {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	open := make([]Incident, 0, len(t.open))
+	for _, incident := range t.open {
+		open = append(open, incident.copy())
+	}
+
+	slices.SortFunc(open, func(a, b Incident) int { return a.Start.Compare(b.Start) })
+
+	return open
+}
+
+// Stop stops tracking and unbinds the tracker from the Events. Open incidents are
+// completed with IncidentEndStopped. Stopping the Events with closeChans also stops the tracker.
+func (t *IncidentTracker) Stop() {
+	t.binding.Unbind()
+
+	select {
+	case <-t.stop:
+	default:
+		close(t.stop)
+	}
+
+	t.wg.Wait()
+}
+
+func (t *IncidentTracker) run() {
+	ticker := time.NewTicker(max(min(t.config.IdleTimeout, t.config.FileWait)/4, time.Millisecond)) //nolint:mnd
+	defer ticker.Stop()
+	defer t.flush()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case event, ok := <-t.input:
+			if !ok {
+				return
+			}
+
+			if event.ID >= 0 { // Library events carry the local time, even during a replay.
+				t.clock, t.clockAt = event.Time, time.Now()
+				t.expire(t.clock)
+			}
+
+			t.observe(event)
+		case <-ticker.C:
+			t.expire(t.now())
+		}
+	}
+}
+
+// now returns the tracker's clock: the Time of the last server event plus the
+// wall time since it arrived, or the wall clock before any event.
+func (t *IncidentTracker) now() time.Time {
+	if t.clock.IsZero() {
+		return time.Now()
+	}
+
+	return t.clock.Add(time.Since(t.clockAt))
+}
+
+// observe adds an event to its camera's incident.
+func (t *IncidentTracker) observe(event Event) {
+	if event.Camera == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	incident := t.open[event.Camera.Number]
+
+	switch event.Type { //nolint:exhaustive // only motion events make incidents.
+	case EventTriggerMotion, EventClassify, EventMotionDetected:
+		if incident != nil && incident.ended {
+			t.complete(incident, IncidentEndNoFile)
+			incident = nil
+		}
+
+		if incident == nil {
+			incident = &Incident{Camera: event.Camera, Start: event.When, opened: event.Time, Human: -99, Vehicle: -99, Animal: -99}
+			t.open[event.Camera.Number] = incident
+		}
+
+		incident.add(event)
+	case EventMotionEnd:
+		if incident != nil && !incident.ended {
+			incident.add(event)
+			incident.ended = true
+			incident.End = event.When
+		}
+	case EventFileWritten:
+		// Continuous capture files are written on their own schedule; they are not the incident's recording.
+		if incident != nil && incident.ended && event.File != nil && event.File.Capture != CaptureContinuous {
+			incident.add(event)
+			incident.File = event.File
+			t.complete(incident, IncidentEndFile)
+		}
+	}
+}
+
+// expire completes incidents that timed out.
+func (t *IncidentTracker) expire(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, incident := range t.open {
+		switch {
+		case now.Sub(incident.opened) >= t.config.MaxDuration:
+			t.complete(incident, IncidentEndMaxDuration)
+		case incident.ended && now.Sub(incident.lastSeen) >= t.config.FileWait:
+			t.complete(incident, IncidentEndNoFile)
+		case !incident.ended && now.Sub(incident.lastSeen) >= t.config.IdleTimeout:
+			t.complete(incident, IncidentEndIdle)
+		}
+	}
+}
+
+// flush completes every open incident when the tracker stops.
+func (t *IncidentTracker) flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, incident := range t.open {
+		t.complete(incident, IncidentEndStopped)
+	}
+}
+
+// complete removes an incident and sends it to the bindings. Call with the lock held.
+func (t *IncidentTracker) complete(incident *Incident, reason IncidentEnd) {
+	delete(t.open, incident.Camera.Number)
+
+	incident.EndedBy = reason
+	if incident.End.IsZero() {
+		incident.End = incident.Events[len(incident.Events)-1].When
+	}
+
+	for _, callback := range t.funcs {
+		go t.call(callback, incident.copy())
+	}
+
+	for _, channel := range t.chans {
+		select {
+		case channel <- incident.copy():
+		default:
+		}
+	}
+}
+
+// call runs a callback with a completed incident, and reports it if it panics.
+func (t *IncidentTracker) call(callback func(Incident), incident Incident) {
+	start := time.Now()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			t.events.callbackPanicked(incident.Events[len(incident.Events)-1], recovered, start)
+		}
+	}()
+
+	callback(incident)
+}
+
+// add merges an event into the incident.
+func (i *Incident) add(event Event) {
+	i.Events = append(i.Events, event)
+	i.lastSeen = event.Time
+
+	for _, reason := range event.Reasons {
+		if !slices.Contains(i.Reasons, reason) {
+			i.Reasons = append(i.Reasons, reason)
+		}
+	}
+
+	if event.Type == EventClassify {
+		i.Human = max(i.Human, event.ClassifyHuman)
+		i.Vehicle = max(i.Vehicle, event.ClassifyVehicle)
+		i.Animal = max(i.Animal, event.ClassifyAnimal)
+	}
+}
+
+// copy returns an incident that does not share slices with the tracker's copy.
+func (i *Incident) copy() Incident {
+	incident := *i
+	incident.Reasons = slices.Clone(i.Reasons)
+	incident.Events = slices.Clone(i.Events)
+
+	return incident
+}
@@ -0,0 +1,138 @@
+package securityspy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+func newIncidentTracker(t *testing.T, config *securityspy.IncidentConfig) (
+	*securityspytest.Server, *securityspy.IncidentTracker, chan securityspy.Incident,
+) {
+	t.Helper()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	tracker := securityspy.NewIncidentTracker(sspy.Events, config)
+	incidents := make(chan securityspy.Incident, 10)
+	tracker.BindChan(incidents)
+
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() {
+		sspy.Events.Stop(false)
+		tracker.Stop()
+	})
+
+	require.Eventually(t, func() bool { return fake.EventStreamClients() == 1 }, time.Second, time.Millisecond)
+
+	return fake, tracker, incidents
+}
+
+func nextIncident(t *testing.T, incidents chan securityspy.Incident) securityspy.Incident {
+	t.Helper()
+
+	select {
+	case incident := <-incidents:
+		return incident
+	case <-time.After(2 * time.Second):
+		t.Fatal("no incident")
+		return securityspy.Incident{}
+	}
+}
+
+func TestIncidentTracker(t *testing.T) {
+	t.Parallel()
+
+	fake, tracker, incidents := newIncidentTracker(t, nil)
+
+	fake.PushEvent(3, "CLASSIFY", "HUMAN", "40")
+	fake.PushEvent(3, "TRIGGER_M", "129") // motion + human.
+	fake.PushEvent(3, "CLASSIFY", "HUMAN", "95", "VEHICLE", "10")
+	fake.PushEvent(3, "TRIGGER_M", "1")
+	fake.PushEvent(2, "TRIGGER_M", "1")
+	fake.PushEvent(3, "MOTION_END")
+	require.Eventually(t, func() bool { return len(tracker.Open()) == 2 }, time.Second, time.Millisecond)
+
+	fake.PushEvent(3, "FILE", "/Volumes/Disk/Door/2019-01-18/01-18-2019 10-17-53 M Door.m4v")
+
+	incident := nextIncident(t, incidents)
+	require.Equal(t, securityspy.IncidentEndFile, incident.EndedBy)
+	require.Equal(t, 3, incident.Camera.Number)
+	require.Equal(t, []securityspy.TriggerEvent{securityspy.TriggerByMotion, securityspy.TriggerByHumanDetection}, incident.Reasons)
+	require.Equal(t, 95, incident.Human)
+	require.Equal(t, 10, incident.Vehicle)
+	require.Equal(t, -99, incident.Animal)
+	require.Len(t, incident.Events, 6)
+	require.False(t, incident.End.Before(incident.Start))
+	require.NotNil(t, incident.File)
+	require.Equal(t, "++getfile/3/2019-01-18/01-18-2019+10-17-53+M+Door.m4v", incident.File.Link.HREF)
+
+	tracker.Stop()
+
+	incident = nextIncident(t, incidents)
+	require.Equal(t, securityspy.IncidentEndStopped, incident.EndedBy)
+	require.Equal(t, 2, incident.Camera.Number)
+}
+
+func TestIncidentTrackerTimeouts(t *testing.T) {
+	t.Parallel()
+
+	fake, _, incidents := newIncidentTracker(t, &securityspy.IncidentConfig{
+		IdleTimeout: 50 * time.Millisecond,
+		FileWait:    50 * time.Millisecond,
+	})
+
+	fake.PushEvent(2, "TRIGGER_M", "1")
+
+	incident := nextIncident(t, incidents)
+	require.Equal(t, securityspy.IncidentEndIdle, incident.EndedBy)
+	require.Equal(t, 2, incident.Camera.Number)
+
+	fake.PushEvent(3, "TRIGGER_M", "1")
+	fake.PushEvent(3, "MOTION_END")
+
+	incident = nextIncident(t, incidents)
+	require.Equal(t, securityspy.IncidentEndNoFile, incident.EndedBy)
+	require.Nil(t, incident.File)
+	require.Len(t, incident.Events, 2)
+}
+
+func TestIncidentTrackerStopAndPanic(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	failures := make(chan securityspy.Event, 1)
+	sspy.Events.BindChan(securityspy.EventCallbackPanic, failures)
+
+	tracker := securityspy.NewIncidentTracker(sspy.Events, &securityspy.IncidentConfig{IdleTimeout: 20 * time.Millisecond})
+	tracker.BindFunc(func(securityspy.Incident) { panic("incident") })
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+	require.Eventually(t, func() bool { return fake.EventStreamClients() == 1 }, time.Second, time.Millisecond)
+
+	fake.PushEvent(3, "TRIGGER_M", "1")
+
+	select {
+	case event := <-failures:
+		require.Equal(t, "incident", event.Failure.Panic)
+		require.Equal(t, securityspy.EventTriggerMotion, event.Failure.Event.Type)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the panic was not reported")
+	}
+
+	require.Len(t, sspy.Events.BindingStats(), 2)
+	tracker.Stop()
+	require.Len(t, sspy.Events.BindingStats(), 1, "Stop removes the tracker's binding")
+}
@@ -0,0 +1,79 @@
+package securityspy
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults for IncidentConfig.
+const (
+	DefaultIncidentIdle        = 2 * time.Minute
+	DefaultIncidentFileWait    = 30 * time.Second
+	DefaultIncidentMaxDuration = 30 * time.Minute
+	// IncidentBuffer is the channel buffer size for events inside an IncidentTracker.
+	IncidentBuffer = 1000
+)
+
+// IncidentEnd says why an incident was completed.
+type IncidentEnd string
+
+// These are the reasons an incident completes.
+const (
+	// IncidentEndFile means MOTION_END arrived, followed by the FILE event for the recording.
+	IncidentEndFile IncidentEnd = "file"
+	// IncidentEndNoFile means MOTION_END arrived, but no FILE event came within FileWait,
+	// or a new motion trigger started the next incident first.
+	IncidentEndNoFile IncidentEnd = "no file"
+	// IncidentEndIdle means the camera sent nothing for IdleTimeout and MOTION_END never came.
+	IncidentEndIdle IncidentEnd = "idle timeout"
+	// IncidentEndMaxDuration means the incident was still open after MaxDuration.
+	IncidentEndMaxDuration IncidentEnd = "max duration"
+	// IncidentEndStopped means the tracker was stopped while the incident was open.
+	IncidentEndStopped IncidentEnd = "stopped"
+)
+
+// IncidentConfig is optional input for NewIncidentTracker. Zero values use the defaults.
+type IncidentConfig struct {
+	// IdleTimeout completes an incident when its camera sends no events for this long.
+	IdleTimeout time.Duration
+	// FileWait is how long to wait for the FILE event after MOTION_END.
+	FileWait time.Duration
+	// MaxDuration completes an incident this long after it started, even if events keep coming.
+	MaxDuration time.Duration
+}
+
+// Incident is one motion episode on one camera: the TRIGGER_M, CLASSIFY,
+// MOTION_END and FILE events SecuritySpy sends for it, merged together.
+type Incident struct {
+	Camera  *Camera
+	Start   time.Time      // Server time of the first event.
+	End     time.Time      // Server time of MOTION_END, or of the last event if it never came.
+	Reasons []TriggerEvent // Every trigger reason seen, in the order first seen.
+	Human   int            // Peak CLASSIFY human score (-99 until a CLASSIFY event arrives).
+	Vehicle int            // Peak CLASSIFY vehicle score (-99 until a CLASSIFY event arrives).
+	Animal  int            // Peak CLASSIFY animal score (-99 until a CLASSIFY event arrives).
+	File    *File          // The recording from the FILE event. Nil if none arrived.
+	Events  []Event        // Every event in the incident, in order.
+	EndedBy IncidentEnd    // Why the incident was completed.
+
+	ended    bool      // MOTION_END arrived; waiting for FILE.
+	lastSeen time.Time // Time of the last event.
+	opened   time.Time // Time of the first event.
+}
+
+// IncidentTracker groups motion events per camera into Incidents and sends completed
+// incidents to bound callbacks and channels. Create one with NewIncidentTracker.
+type IncidentTracker struct {
+	events  *Events
+	config  IncidentConfig
+	binding *Binding // Sends every event to input.
+	input   chan Event
+	stop    chan struct{}
+	clock   time.Time // Time of the last server event. Only the run go routine uses these two.
+	clockAt time.Time // Wall time the last server event arrived.
+	wg      sync.WaitGroup
+	mu      sync.Mutex // Protects the fields below.
+	open    map[int]*Incident
+	funcs   []func(Incident)
+	chans   []chan Incident
+}
@@ -0,0 +1,286 @@
+// Package journal stores SecuritySpy events on disk and answers queries about them.
+// Events are appended as JSON lines to segment files in one directory. A new
+// segment starts when the current one gets too big or too old, and whole segments
+// are pruned by age and total size. It has no dependencies outside the standard library.
+//
+//	jrnl, err := journal.Open("/var/lib/myapp/events", nil)
+//	jrnl.BindEvents(sspy.Events)
+//	sspy.Events.Watch(time.Second*10, true)
+//	page, err := jrnl.Query(&journal.Query{Cameras: []int{3}, Types: []securityspy.EventType{securityspy.EventOffline}, Reverse: true, Limit: 1})
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golift.io/securityspy/v2"
+)
+
+const (
+	// DefaultSegmentSize is the size a segment may reach before a new one starts.
+	DefaultSegmentSize = 8 << 20
+	// DefaultSegmentAge is how long a segment takes new events before a new one starts.
+	DefaultSegmentAge = 24 * time.Hour
+	// segmentExt is the file extension of segment files.
+	segmentExt = ".jsonl"
+	// segmentFormat names segment files by the time of their first event, so they sort by name.
+	segmentFormat = "20060102T150405.000000000Z"
+)
+
+// ErrClosed is returned by Append after Close.
+var ErrClosed = errors.New("journal is closed")
+
+// Config is optional input for Open. Zero values use the defaults.
+type Config struct {
+	// MaxAge prunes segments once every event in them is older than this. 0 keeps events forever.
+	MaxAge time.Duration
+	// MaxSize prunes the oldest segments while the journal is bigger than this many bytes.
+	// The current segment is never pruned. 0 has no limit.
+	MaxSize int64
+	// SegmentSize starts a new segment when the current one reaches this many bytes.
+	SegmentSize int64
+	// SegmentAge starts a new segment when the current one is this old.
+	SegmentAge time.Duration
+}
+
+// Journal is an event store in a directory. Create one with Open.
+type Journal struct {
+	dir     string
+	config  Config
+	mu      sync.Mutex // Protects the fields below.
+	file    *os.File   // Current segment. Nil until the first Append after Open or rotation.
+	start   time.Time  // Time of the first event in the current segment.
+	size    int64      // Size of the current segment.
+	lastErr error
+	closed  bool
+}
+
+// segment is one file in the journal.
+type segment struct {
+	name  string
+	start time.Time
+	size  int64
+}
+
+// Open opens or creates a journal in dir. Config may be nil.
+// Segments that are already too old or too big are pruned.
+func Open(dir string, config *Config) (*Journal, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	jrnl := &Journal{dir: dir, config: *config}
+
+	if jrnl.config.SegmentSize <= 0 {
+		jrnl.config.SegmentSize = DefaultSegmentSize
+	}
+
+	if jrnl.config.SegmentAge <= 0 {
+		jrnl.config.SegmentAge = DefaultSegmentAge
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("creating journal directory: %w", err)
+	}
+
+	jrnl.mu.Lock()
+	defer jrnl.mu.Unlock()
+
+	if err := jrnl.prune(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return jrnl, nil
+}
+
+// BindEvents appends every event from events to the journal. Events are written one at a
+// time in stream order. Write errors do not stop the binding; read the last one with Err.
+// Call this before Events.Watch. Stop appending with Binding.Unbind.
+func (j *Journal) BindEvents(events *securityspy.Events) *securityspy.Binding {
+	return events.BindFuncConfig(securityspy.EventAllEvents, func(event securityspy.Event) {
+		_ = j.Append(event.Record())
+	}, &securityspy.BindConfig{Order: securityspy.OrderGlobal})
+}
+
+// Err returns the last error from Append, if any.
+func (j *Journal) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.lastErr
+}
+
+// Append writes a record to the journal, starting a new segment first if the
+// current one is full or old. Records should be appended in Time order.
+func (j *Journal) Append(record securityspy.EventRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return j.failed(fmt.Errorf("encoding event: %w", err))
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return ErrClosed
+	}
+
+	if err := j.rotate(record.Time); err != nil {
+		j.lastErr = err
+		return err
+	}
+
+	n, err := j.file.Write(append(line, '\n'))
+	j.size += int64(n)
+
+	if err != nil {
+		j.lastErr = fmt.Errorf("writing journal: %w", err)
+		return j.lastErr
+	}
+
+	return nil
+}
+
+// Close closes the current segment. Append returns ErrClosed afterward; Query still works.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.closed = true
+
+	if j.file == nil {
+		return nil
+	}
+
+	err := j.file.Close()
+	j.file = nil
+
+	if err != nil {
+		return fmt.Errorf("closing journal: %w", err)
+	}
+
+	return nil
+}
+
+func (j *Journal) failed(err error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.lastErr = err
+
+	return err
+}
+
+// rotate starts a new segment if there is none, or the current one is full or old.
+// Starting a segment prunes old ones. Call with the lock held.
+func (j *Journal) rotate(now time.Time) error {
+	if j.file != nil && j.size < j.config.SegmentSize && now.Sub(j.start) < j.config.SegmentAge {
+		return nil
+	}
+
+	if j.file != nil {
+		if err := j.file.Close(); err != nil {
+			return fmt.Errorf("closing journal segment: %w", err)
+		}
+
+		j.file = nil
+	}
+
+	name := now.UTC().Format(segmentFormat) + segmentExt
+
+	file, err := os.OpenFile(filepath.Join(j.dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("creating journal segment: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("creating journal segment: %w", err)
+	}
+
+	j.file, j.start, j.size = file, now, info.Size()
+
+	return j.prune(now)
+}
+
+// prune removes segments that are too old, then the oldest ones while the journal
+// is too big. The current segment is kept. Call with the lock held.
+func (j *Journal) prune(now time.Time) error {
+	segments, err := j.segments()
+	if err != nil {
+		return err
+	}
+
+	current := ""
+	if j.file != nil {
+		current = filepath.Base(j.file.Name())
+	}
+
+	var total int64
+	for _, seg := range segments {
+		total += seg.size
+	}
+
+	for idx, seg := range segments {
+		if seg.name == current {
+			break
+		}
+
+		// A segment's events all happened before the next segment started.
+		tooOld := j.config.MaxAge > 0 && idx+1 < len(segments) && now.Sub(segments[idx+1].start) > j.config.MaxAge
+		tooBig := j.config.MaxSize > 0 && total > j.config.MaxSize
+
+		if !tooOld && !tooBig {
+			break
+		}
+
+		if err := os.Remove(filepath.Join(j.dir, seg.name)); err != nil {
+			return fmt.Errorf("pruning journal: %w", err)
+		}
+
+		total -= seg.size
+	}
+
+	return nil
+}
+
+// segments lists the segment files, oldest first.
+func (j *Journal) segments() ([]segment, error) {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading journal directory: %w", err)
+	}
+
+	segments := []segment{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		start, err := time.Parse(segmentFormat, strings.TrimSuffix(name, segmentExt))
+		if err != nil {
+			continue // Not ours.
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("reading journal directory: %w", err)
+		}
+
+		segments = append(segments, segment{name: name, start: start, size: info.Size()})
+	}
+
+	slices.SortFunc(segments, func(a, b segment) int { return strings.Compare(a.name, b.name) })
+
+	return segments, nil
+}
@@ -0,0 +1,164 @@
+package journal_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/journal"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+var start = time.Date(2026, 10, 15, 20, 0, 0, 0, time.UTC) //nolint:gochecknoglobals
+
+func record(minutes, camera int, eventType securityspy.EventType) securityspy.EventRecord {
+	at := start.Add(time.Duration(minutes) * time.Minute)
+	return securityspy.EventRecord{Time: at, When: at, ID: minutes + 1, Type: eventType, Camera: camera}
+}
+
+func ids(page *journal.Page) []int {
+	ids := []int{}
+	for _, record := range page.Records {
+		ids = append(ids, record.ID)
+	}
+
+	return ids
+}
+
+func TestJournalQuery(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	jrnl, err := journal.Open(dir, &journal.Config{SegmentAge: time.Hour})
+	require.NoError(t, err)
+
+	human := record(60, 3, securityspy.EventTriggerMotion)
+	human.Reasons = []securityspy.TriggerEvent{securityspy.TriggerByMotion, securityspy.TriggerByHumanDetection}
+	classify := record(61, 3, securityspy.EventClassify)
+	classify.Scores = map[string]int{"HUMAN": 95}
+
+	for _, rec := range []securityspy.EventRecord{
+		record(0, 3, securityspy.EventOffline),
+		record(1, 3, securityspy.EventOnline),
+		record(2, 2, securityspy.EventOffline),
+		human,
+		classify,
+		record(125, 3, securityspy.EventOffline),
+		record(126, -1, securityspy.EventKeepAlive),
+	} {
+		require.NoError(t, jrnl.Append(rec))
+	}
+
+	require.NoError(t, jrnl.Close())
+	require.ErrorIs(t, jrnl.Append(record(127, 3, securityspy.EventOnline)), journal.ErrClosed)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3, "a new segment starts every SegmentAge")
+
+	jrnl, err = journal.Open(dir, nil)
+	require.NoError(t, err)
+
+	last, err := jrnl.Query(&journal.Query{
+		Cameras: []int{3},
+		Types:   []securityspy.EventType{securityspy.EventOffline},
+		Reverse: true,
+		Limit:   1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{126}, ids(last), "the last time camera 3 went offline")
+	require.NotEmpty(t, last.Next)
+
+	for query, want := range map[*journal.Query][]int{
+		{Reasons: []securityspy.TriggerEvent{securityspy.TriggerByHumanDetection}}: {61},
+		{MinScores: map[string]int{"HUMAN": 90}}:                                   {62},
+		{MinScores: map[string]int{"HUMAN": 99}}:                                   {},
+		{From: start.Add(time.Minute), To: start.Add(62 * time.Minute)}:            {2, 3, 61, 62},
+		{From: start.Add(2 * time.Hour)}:                                           {126, 127},
+		{Types: []securityspy.EventType{securityspy.EventOffline}, Reverse: true}:  {126, 3, 1},
+	} {
+		page, err := jrnl.Query(query)
+		require.NoError(t, err)
+		require.Equal(t, want, ids(page), "%+v", query)
+		require.Empty(t, page.Next)
+	}
+
+	query := &journal.Query{Limit: 3, Reverse: true}
+	pages := [][]int{}
+
+	for {
+		page, err := jrnl.Query(query)
+		require.NoError(t, err)
+
+		pages = append(pages, ids(page))
+		if query.Cursor = page.Next; query.Cursor == "" {
+			break
+		}
+	}
+
+	require.Equal(t, [][]int{{127, 126, 62}, {61, 3, 2}, {1}}, pages)
+
+	_, err = jrnl.Query(&journal.Query{Cursor: "../../etc/passwd:1"})
+	require.ErrorIs(t, err, journal.ErrBadCursor)
+}
+
+func TestJournalPrune(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	jrnl, err := journal.Open(dir, &journal.Config{SegmentAge: time.Hour, MaxAge: 150 * time.Minute})
+	require.NoError(t, err)
+
+	for hour := range 6 {
+		require.NoError(t, jrnl.Append(record(hour*60, 3, securityspy.EventOnline)))
+	}
+
+	page, err := jrnl.Query(&journal.Query{})
+	require.NoError(t, err)
+	require.Equal(t, []int{121, 181, 241, 301}, ids(page), "segments older than MaxAge are pruned")
+
+	dir = t.TempDir()
+	jrnl, err = journal.Open(dir, &journal.Config{SegmentSize: 1, MaxSize: 500})
+	require.NoError(t, err)
+
+	for minute := range 20 {
+		require.NoError(t, jrnl.Append(record(minute, 3, securityspy.EventOnline)))
+	}
+
+	page, err = jrnl.Query(&journal.Query{})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.Records)
+	require.Less(t, len(page.Records), 20, "the oldest segments are pruned past MaxSize")
+	require.Equal(t, 20, page.Records[len(page.Records)-1].ID)
+}
+
+func TestJournalBindEvents(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	jrnl, err := journal.Open(t.TempDir(), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = jrnl.Close() })
+
+	jrnl.BindEvents(sspy.Events)
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	require.Eventually(t, func() bool { return fake.EventStreamClients() == 1 }, time.Second, time.Millisecond)
+	fake.PushEvent(3, "TRIGGER_M", "129")
+
+	query := &journal.Query{Cameras: []int{3}, Reasons: []securityspy.TriggerEvent{securityspy.TriggerByHumanDetection}}
+
+	require.Eventually(t, func() bool {
+		page, err := jrnl.Query(query)
+		return err == nil && len(page.Records) == 1 && page.Records[0].CameraName == "Door"
+	}, time.Second, time.Millisecond)
+	require.NoError(t, jrnl.Err())
+}
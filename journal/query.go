@@ -0,0 +1,206 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golift.io/securityspy/v2"
+)
+
+// DefaultLimit is the page size when Query.Limit is 0.
+const DefaultLimit = 100
+
+// ErrBadCursor is returned by Query when Cursor did not come from a Page.
+var ErrBadCursor = errors.New("invalid journal cursor")
+
+// Query selects records from the journal. Empty fields match everything.
+// A record must match every field that is set; within a field, any listed value matches.
+type Query struct {
+	From    time.Time                  // Records received at or after this time.
+	To      time.Time                  // Records received before this time.
+	Cameras []int                      // Camera numbers.
+	Types   []securityspy.EventType    // Event types.
+	Reasons []securityspy.TriggerEvent // Trigger reasons. Only trigger events have reasons.
+	// MinScores matches CLASSIFY records with a score at or above the minimum
+	// for any listed class, ie. {"HUMAN": 80}. Other records do not match when this is set.
+	MinScores map[string]int
+	Reverse   bool   // Newest records first.
+	Limit     int    // Records per page. 0 uses DefaultLimit.
+	Cursor    string // Page.Next from the previous page. Empty starts at the beginning (or end, with Reverse).
+}
+
+// Page is one page of query results.
+type Page struct {
+	Records []securityspy.EventRecord
+	// Next is the Cursor for the next page. Empty when there are no more records.
+	Next string
+}
+
+// Query returns one page of records that match the query, oldest first unless
+// query.Reverse is set. Pass Page.Next back in query.Cursor to get the next page.
+func (j *Journal) Query(query *Query) (*Page, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	segments, err := j.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Reverse {
+		slices.Reverse(segments)
+	}
+
+	segName, skip, err := parseCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{}
+
+	for segIdx, seg := range segments {
+		if segName != "" && seg.name != segName {
+			continue
+		}
+
+		if query.skipSegment(segments, segIdx) {
+			segName, skip = "", 0
+			continue
+		}
+
+		records, err := j.readSegment(seg.name)
+		if err != nil {
+			return nil, err
+		}
+
+		// Cursors hold positions in file order, so appends do not move them.
+		first := skip
+		if query.Reverse && segName != "" {
+			first = len(records) - 1 - skip
+		}
+
+		for idx := max(first, 0); idx < len(records); idx++ {
+			pos := idx
+			if query.Reverse {
+				pos = len(records) - 1 - idx
+			}
+
+			if !query.match(&records[pos]) {
+				continue
+			}
+
+			if len(page.Records) == limit {
+				page.Next = seg.name + ":" + strconv.Itoa(pos)
+				return page, nil
+			}
+
+			page.Records = append(page.Records, records[pos])
+		}
+
+		segName, skip = "", 0
+	}
+
+	if segName != "" {
+		return nil, fmt.Errorf("%w: segment was pruned", ErrBadCursor)
+	}
+
+	return page, nil
+}
+
+// skipSegment returns true if no record in segments[idx] can be in the time range.
+// Segments hold the records received between their start and the next segment's start.
+func (q *Query) skipSegment(segments []segment, idx int) bool {
+	first, next := segments[idx].start, time.Time{}
+
+	if q.Reverse && idx > 0 {
+		next = segments[idx-1].start
+	} else if !q.Reverse && idx+1 < len(segments) {
+		next = segments[idx+1].start
+	}
+
+	return (!q.To.IsZero() && !first.Before(q.To)) || (!q.From.IsZero() && !next.IsZero() && next.Before(q.From))
+}
+
+// match returns true if a record passes every filter that is set.
+func (q *Query) match(record *securityspy.EventRecord) bool {
+	switch {
+	case !q.From.IsZero() && record.Time.Before(q.From),
+		!q.To.IsZero() && !record.Time.Before(q.To),
+		len(q.Cameras) > 0 && !slices.Contains(q.Cameras, record.Camera),
+		len(q.Types) > 0 && !slices.Contains(q.Types, record.Type),
+		len(q.Reasons) > 0 && !slices.ContainsFunc(record.Reasons, func(r securityspy.TriggerEvent) bool {
+			return slices.Contains(q.Reasons, r)
+		}):
+		return false
+	case len(q.MinScores) == 0:
+		return true
+	}
+
+	for class, minimum := range q.MinScores {
+		if score, ok := record.Scores[class]; ok && score >= minimum {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readSegment reads every record in a segment. A partly written last line is skipped.
+func (j *Journal) readSegment(name string) ([]securityspy.EventRecord, error) {
+	file, err := os.Open(filepath.Join(j.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("reading journal segment: %w", err)
+	}
+	defer file.Close()
+
+	records := []securityspy.EventRecord{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20) //nolint:mnd // events are much smaller than 1MB.
+
+	for scanner.Scan() {
+		var record securityspy.EventRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading journal segment: %w", err)
+	}
+
+	return records, nil
+}
+
+// parseCursor splits a cursor into a segment name and a record position.
+func parseCursor(cursor string) (string, int, error) {
+	if cursor == "" {
+		return "", 0, nil
+	}
+
+	name, pos, ok := strings.Cut(cursor, ":")
+	if !ok || !strings.HasSuffix(name, segmentExt) || strings.ContainsAny(name, `/\`) {
+		return "", 0, ErrBadCursor
+	}
+
+	skip, err := strconv.Atoi(pos)
+	if err != nil || skip < 0 {
+		return "", 0, ErrBadCursor
+	}
+
+	return name, skip, nil
+}
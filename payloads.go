@@ -0,0 +1,130 @@
+package securityspy
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Payload returns the parsed body of an event from the stream. Use a type switch to
+// read it; see Payload. It is nil for library events and events that could not be split.
+func (e *Event) Payload() Payload {
+	return e.payload
+}
+
+// scores returns the CLASSIFY scores by class, without the classes SecuritySpy did not score.
+func (e *Event) scores() map[string]int {
+	scores := map[string]int{"HUMAN": e.ClassifyHuman, "VEHICLE": e.ClassifyVehicle, "ANIMAL": e.ClassifyAnimal}
+	if payload, ok := e.payload.(*ClassifyPayload); ok {
+		scores = payload.Scores
+	}
+
+	found := make(map[string]int)
+
+	for class, score := range scores {
+		if score != noScore {
+			found[class] = score
+		}
+	}
+
+	return found
+}
+
+// parsePayload fills in the event's payload, and the fields that come from it, from the
+// event's tokens. parts[0] is the event type as sent.
+func (e *Events) parsePayload(event *Event, parts []string, snap *Snapshot, cameraNum int) {
+	var err error
+
+	switch event.Type {
+	case EventClassify:
+		event.payload = parseClassify(event, parts[1:])
+	case EventFileWritten:
+		payload := &FilePayload{Path: strings.TrimPrefix(event.Msg, string(EventFileWritten)+" ")}
+		event.payload = payload
+
+		if cameraNum < 0 {
+			return
+		}
+
+		if event.File, err = e.server.Files.fromPath(snap, cameraNum, payload.Path); err != nil {
+			event.Errors = append(event.Errors, err)
+		}
+
+		payload.File = event.File
+	case EventTriggerAction, EventTriggerMotion:
+		event.payload = e.parseTrigger(event, parts[1:], snap.Info.ParsedVersion())
+	case EventArmContinuous, EventArmMotion, EventArmActions,
+		EventDisarmContinuous, EventDisarmMotion, EventDisarmActions:
+		event.payload = &ArmPayload{
+			Mode:  CameraMode(parts[0][len(parts[0])-1]),
+			Armed: strings.HasPrefix(parts[0], "ARM_"),
+			Extra: tokens(parts[1:]),
+		}
+	case EventSecSpyError:
+		event.payload = &ErrorPayload{Text: strings.TrimPrefix(strings.TrimPrefix(event.Msg, string(EventSecSpyError)), " ")}
+	default:
+		event.payload = &RawPayload{Type: parts[0], Fields: tokens(parts[1:])}
+	}
+}
+
+// parseClassify reads class and score pairs, ie. HUMAN 5 VEHICLE 95. Classes missing
+// from the event stay at -99 in the Classify fields, the same as SecuritySpy uses for no score.
+func parseClassify(event *Event, parts []string) *ClassifyPayload {
+	payload := &ClassifyPayload{Scores: make(map[string]int)}
+
+	for idx := 0; idx < len(parts); idx++ {
+		value, err := 0, strconv.ErrSyntax
+		if idx+1 < len(parts) {
+			value, err = strconv.Atoi(parts[idx+1])
+		}
+
+		if err != nil || parts[idx] == "" {
+			payload.Extra = append(payload.Extra, parts[idx])
+			continue
+		}
+
+		payload.Scores[parts[idx]] = value
+		idx++
+	}
+
+	event.ClassifyHuman, event.ClassifyVehicle, event.ClassifyAnimal = noScore, noScore, noScore
+
+	for class, field := range map[string]*int{
+		"HUMAN":   &event.ClassifyHuman,
+		"VEHICLE": &event.ClassifyVehicle,
+		"ANIMAL":  &event.ClassifyAnimal,
+	} {
+		if score, ok := payload.Scores[class]; ok {
+			*field = score
+		}
+	}
+
+	return payload
+}
+
+// parseTrigger reads the reason bitmask and decodes the reasons.
+func (e *Events) parseTrigger(event *Event, parts []string, version Version) *TriggerPayload {
+	if len(parts) == 0 {
+		return &TriggerPayload{}
+	}
+
+	payload := &TriggerPayload{Extra: tokens(parts[1:])}
+
+	var err error
+	if event.ReasonMask, err = strconv.Atoi(parts[0]); err != nil {
+		payload.Extra = parts
+	}
+
+	e.decodeReasons(event, version)
+	payload.Mask, payload.Reasons, payload.UnknownReasons = event.ReasonMask, event.Reasons, event.UnknownReasons
+
+	return payload
+}
+
+// tokens returns nil instead of an empty slice, so payloads without extra tokens compare equal.
+func tokens(parts []string) []string {
+	if len(parts) == 0 {
+		return nil
+	}
+
+	return parts
+}
@@ -0,0 +1,65 @@
+package securityspy
+
+// noScore is the CLASSIFY score SecuritySpy sends for a class it did not score.
+const noScore = -99
+
+// Payload is the parsed body of an event from the stream, after the event type.
+// Get it from Event.Payload and use a type switch to pick the kind:
+//
+//	switch payload := event.Payload().(type) {
+//	case *securityspy.ClassifyPayload:
+//		fmt.Println(payload.Scores["HUMAN"])
+//	case *securityspy.FilePayload:
+//		fmt.Println(payload.Path)
+//	}
+type Payload interface {
+	payload()
+}
+
+// ClassifyPayload is the body of a CLASSIFY event.
+type ClassifyPayload struct {
+	// Scores has every class in the event, not only HUMAN, VEHICLE and ANIMAL.
+	// SecuritySpy sends -99 for a class it did not score.
+	Scores map[string]int
+	Extra  []string // Tokens that were not a class followed by a score.
+}
+
+// TriggerPayload is the body of a TRIGGER_M or TRIGGER_A event.
+type TriggerPayload struct {
+	Mask           int            // Reason bitmask, the same as Event.ReasonMask.
+	Reasons        []TriggerEvent // Reasons decoded from Mask, the same as Event.Reasons.
+	UnknownReasons int            // Bits in Mask the ReasonTable does not know.
+	Extra          []string       // Tokens after the bitmask, or the bitmask itself if it is not a number.
+}
+
+// FilePayload is the body of a FILE event.
+type FilePayload struct {
+	Path string // Path of the saved file on the server.
+	File *File  // The same as Event.File. Nil if the path could not be parsed.
+}
+
+// ArmPayload is the body of an ARM_* or DISARM_* event.
+type ArmPayload struct {
+	Mode  CameraMode // CameraModeContinuous, CameraModeMotion or CameraModeActions.
+	Armed bool       // True for ARM_*, false for DISARM_*.
+	Extra []string   // Tokens after the event type.
+}
+
+// ErrorPayload is the body of an ERROR event.
+type ErrorPayload struct {
+	Text string // Everything after ERROR, as SecuritySpy sent it.
+}
+
+// RawPayload is the body of every other event, including event types this
+// library does not know, split into tokens.
+type RawPayload struct {
+	Type   string   // Event type as sent, even when Event.Type is EventUnknownEvent.
+	Fields []string // Tokens after the event type.
+}
+
+func (*ClassifyPayload) payload() {}
+func (*TriggerPayload) payload()  {}
+func (*FilePayload) payload()     {}
+func (*ArmPayload) payload()      {}
+func (*ErrorPayload) payload()    {}
+func (*RawPayload) payload()      {}
@@ -0,0 +1,158 @@
+package securityspy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golift.io/securityspy/v2/server"
+)
+
+// Start refreshes the server every interval until Stop is called. After a failed
+// refresh the delay doubles with each consecutive failure, up to maxBackoff.
+// An interval of 0 uses DefaultPollInterval and a maxBackoff of 0 uses DefaultPollMaxBackoff.
+// The first refresh happens one interval from now; call Refresh first if you need data sooner.
+// A poll is skipped if another refresh (ie. on CONFIGCHANGE) finished while it was waiting.
+func (p *Poller) Start(interval, maxBackoff time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultPollMaxBackoff
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.health.Polling = true
+
+	p.wg.Go(func() { p.poll(ctx, interval, max(interval, maxBackoff)) })
+}
+
+// Stop stops the poller and waits for a running refresh to return. It may be started again.
+func (p *Poller) Stop() {
+	p.mu.Lock()
+	cancel := p.cancel
+	p.cancel = nil
+	p.health.Polling = false
+	p.health.NextRefresh = time.Time{}
+	p.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		p.wg.Wait()
+	}
+}
+
+// Health returns the refresh state. It includes refreshes done by Events.Watch,
+// but not direct calls to Server.Refresh.
+func (p *Poller) Health() Health {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.health
+}
+
+// Refresh refreshes the server now and records the result in Health. If a refresh is
+// already running, this waits for it and returns its result instead of starting another.
+// If the caller that started it is canceled, the waiters start a new refresh instead.
+func (p *Poller) Refresh(ctx context.Context) error {
+	p.mu.Lock()
+
+	for call := p.flight; call != nil; call = p.flight {
+		p.mu.Unlock()
+
+		select {
+		case <-call.done:
+			if !call.canceled {
+				return call.err
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for refresh: %w", ctx.Err())
+		}
+
+		p.mu.Lock()
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	p.flight = call
+	p.mu.Unlock()
+
+	call.err = p.server.RefreshContext(ctx)
+	call.canceled = ctx.Err() != nil
+
+	p.mu.Lock()
+	p.flight = nil
+	p.record(ctx, call.err)
+	p.mu.Unlock()
+	close(call.done)
+
+	switch {
+	case ctx.Err() != nil:
+	case call.err != nil:
+		p.server.Events.custom(EventWatcherRefreshFail, -9997, -1, call.err.Error())
+	default:
+		p.server.Events.custom(EventWatcherRefreshed, -9998, -1, EventName(EventWatcherRefreshed))
+	}
+
+	return call.err
+}
+
+// record updates health after a refresh. Refreshes canceled by their caller are not failures.
+func (p *Poller) record(ctx context.Context, err error) {
+	now := time.Now()
+
+	switch {
+	case err == nil:
+		p.health.LastAttempt = now
+		p.health.LastSuccess = now
+		p.health.ConsecutiveFailures = 0
+	case ctx.Err() == nil:
+		p.health.LastAttempt = now
+		p.health.LastError = err
+		p.health.LastErrorTime = now
+		p.health.ConsecutiveFailures++
+	}
+}
+
+func (p *Poller) poll(ctx context.Context, interval, maxBackoff time.Duration) {
+	backoff := server.RetryPolicy{
+		BaseDelay: server.Duration{Duration: interval},
+		MaxDelay:  server.Duration{Duration: maxBackoff},
+	}
+
+	for {
+		health := p.Health()
+
+		delay := interval
+		if health.ConsecutiveFailures > 0 {
+			delay = backoff.Delay(health.ConsecutiveFailures + 1)
+		}
+
+		p.mu.Lock()
+		p.health.NextRefresh = time.Now().Add(delay)
+		p.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if p.Health().LastAttempt.After(health.LastAttempt) {
+			continue // Something else refreshed while we waited.
+		}
+
+		_ = p.Refresh(ctx)
+	}
+}
@@ -0,0 +1,129 @@
+package securityspy_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+	"golift.io/securityspy/v2/server"
+)
+
+func TestPollerHealth(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	config := fake.Config()
+	config.Retry = server.RetryPolicy{MaxAttempts: 1}
+	sspy := securityspy.NewMust(config)
+
+	sspy.Poller.Start(5*time.Millisecond, 20*time.Millisecond)
+	t.Cleanup(sspy.Poller.Stop)
+
+	require.Eventually(t, func() bool { return !sspy.Poller.Health().LastSuccess.IsZero() }, time.Second, time.Millisecond)
+	require.True(t, sspy.Poller.Health().Polling)
+	require.Equal(t, "Door", sspy.Snapshot().Cameras.ByNum(3).Name)
+
+	fake.Handle("/++systemInfo", func(resp http.ResponseWriter, _ *http.Request) {
+		http.Error(resp, "down", http.StatusInternalServerError)
+	})
+	require.Eventually(t, func() bool { return sspy.Poller.Health().ConsecutiveFailures >= 2 }, time.Second, time.Millisecond)
+
+	health := sspy.Poller.Health()
+	require.Error(t, health.LastError)
+	require.True(t, health.LastErrorTime.After(health.LastSuccess))
+	require.Greater(t, health.NextRefresh.Sub(health.LastAttempt), 5*time.Millisecond, "failures back off")
+
+	fake.Handle("/++systemInfo", nil)
+	require.Eventually(t, func() bool { return sspy.Poller.Health().ConsecutiveFailures == 0 }, time.Second, time.Millisecond)
+
+	sspy.Poller.Stop()
+	require.False(t, sspy.Poller.Health().Polling)
+	require.True(t, sspy.Poller.Health().NextRefresh.IsZero())
+}
+
+func TestPollerSharedRefresh(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	release := make(chan struct{})
+
+	fake.Handle("/++systemInfo", func(resp http.ResponseWriter, _ *http.Request) {
+		<-release
+		http.Error(resp, "slow and broken", http.StatusInternalServerError)
+	})
+
+	config := fake.Config()
+	config.Retry = server.RetryPolicy{MaxAttempts: 1}
+	sspy := securityspy.NewMust(config)
+	events := make(chan securityspy.Event, 10)
+	sspy.Events.BindChan(securityspy.EventWatcherRefreshFail, events)
+	sspy.Events.Watch(10*time.Millisecond, true)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() { require.ErrorIs(t, sspy.Poller.Refresh(context.Background()), server.ErrBadStatus) })
+	}
+
+	require.Eventually(t, func() bool { return fake.CountRequests("/++systemInfo") == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond) // Let the other callers join the running refresh.
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, 1, fake.CountRequests("/++systemInfo"), "concurrent refreshes share one request")
+	require.Equal(t, 1, sspy.Poller.Health().ConsecutiveFailures)
+
+	select {
+	case event := <-events:
+		require.Equal(t, securityspy.EventWatcherRefreshFail, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("no refresh event")
+	}
+
+	select {
+	case event := <-events:
+		t.Fatalf("one refresh publishes one event, got another: %v", event.Msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPollerRefreshLeaderCanceled(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	fake.Handle("/++systemInfo", func(_ http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done() // Hangs until the caller gives up.
+	})
+
+	config := fake.Config()
+	config.Retry = server.RetryPolicy{MaxAttempts: 1}
+	sspy := securityspy.NewMust(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	waiter := make(chan error, 1)
+
+	go func() { leader <- sspy.Poller.Refresh(ctx) }()
+	require.Eventually(t, func() bool { return fake.CountRequests("/++systemInfo") == 1 }, time.Second, time.Millisecond)
+
+	go func() { waiter <- sspy.Poller.Refresh(context.Background()) }()
+	time.Sleep(20 * time.Millisecond) // Let the waiter join the running refresh.
+	fake.Handle("/++systemInfo", nil)
+	cancel()
+
+	require.ErrorIs(t, nextEvent(t, leader), context.Canceled)
+	require.NoError(t, nextEvent(t, waiter), "a waiter with a live context refreshes again")
+	require.Equal(t, 2, fake.CountRequests("/++systemInfo"))
+	require.Zero(t, sspy.Poller.Health().ConsecutiveFailures)
+}
@@ -0,0 +1,46 @@
+package securityspy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is used when Poller.Start is given an interval of 0.
+	DefaultPollInterval = time.Minute
+	// DefaultPollMaxBackoff caps the delay between refreshes after repeated failures.
+	DefaultPollMaxBackoff = 15 * time.Minute
+)
+
+// Poller refreshes a server in the background and tracks refresh health.
+// Every Server has one at Server.Poller. Refreshes done by Events.Watch on
+// CONFIGCHANGE go through the poller too, so the two never run at the same
+// time: a refresh requested while another is running waits for and shares
+// its result. Both paths publish EventWatcherRefreshed and EventWatcherRefreshFail.
+type Poller struct {
+	server *Server
+	mu     sync.Mutex // Protects the fields below.
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	health Health
+	flight *refreshCall
+}
+
+// refreshCall is one shared refresh.
+type refreshCall struct {
+	done     chan struct{}
+	err      error
+	canceled bool // The caller's context ended, so err says nothing about the server.
+}
+
+// Health is the refresh state of a server, from Poller.Health.
+type Health struct {
+	Polling             bool      // The poller is running.
+	LastAttempt         time.Time // When the last refresh finished.
+	LastSuccess         time.Time // When the last successful refresh finished.
+	LastError           error     // Error from the last failed refresh.
+	LastErrorTime       time.Time // When the last failed refresh finished.
+	ConsecutiveFailures int       // Failed refreshes since the last success.
+	NextRefresh         time.Time // When the poller refreshes next. Zero if it is not running.
+}
@@ -0,0 +1,54 @@
+package securityspy
+
+import "time"
+
+// EventRecord is an Event reduced to plain values, so it can be stored or sent
+// somewhere as JSON. Create one with Event.Record.
+type EventRecord struct {
+	Time       time.Time      `json:"time"` // Local time the event was received.
+	When       time.Time      `json:"when"` // Event time according to the server.
+	ID         int            `json:"id"`
+	Type       EventType      `json:"type"`
+	Camera     int            `json:"camera"` // -1 if the event has no camera.
+	CameraName string         `json:"cameraName,omitempty"`
+	Msg        string         `json:"msg"`
+	ReasonMask int            `json:"reasonMask,omitempty"`
+	Reasons    []TriggerEvent `json:"reasons,omitempty"`
+	// Scores has the CLASSIFY scores by class, ie. HUMAN, for every class the server scored.
+	Scores map[string]int `json:"scores,omitempty"`
+	File   string         `json:"file,omitempty"` // Server path from a FILE event.
+	Errors []string       `json:"errors,omitempty"`
+}
+
+// Record returns the event as an EventRecord.
+func (e *Event) Record() EventRecord {
+	record := EventRecord{
+		Time:       e.Time,
+		When:       e.When,
+		ID:         e.ID,
+		Type:       e.Type,
+		Camera:     -1,
+		Msg:        e.Msg,
+		ReasonMask: e.ReasonMask,
+		Reasons:    e.Reasons,
+	}
+
+	if e.Camera != nil {
+		record.Camera = e.Camera.Number
+		record.CameraName = e.Camera.Name
+	}
+
+	if e.Type == EventClassify {
+		record.Scores = e.scores()
+	}
+
+	if e.File != nil {
+		record.File = e.File.Path
+	}
+
+	for _, err := range e.Errors {
+		record.Errors = append(record.Errors, err.Error())
+	}
+
+	return record
+}
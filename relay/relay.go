@@ -0,0 +1,336 @@
+// Package relay re-serves SecuritySpy events to many HTTP clients, like browser
+// dashboards, as Server-Sent Events or WebSocket messages. All clients share the
+// one ++eventStream connection held by Events, and receive events as
+// securityspy.EventRecord JSON, so server credentials and URLs never reach them.
+//
+//	rly := relay.New(&relay.Config{WebSocket: true})
+//	rly.BindEvents(sspy.Events)
+//	sspy.Events.Watch(time.Second*10, true)
+//	http.Handle("/events", rly)
+//
+// Clients pick events with the camera and type query parameters, which may be
+// repeated or comma separated, ie. /events?camera=3,Porch&type=TRIGGER_M. Cameras
+// match by number or name. Browsers resume after a reconnect with the Last-Event-ID
+// header, which EventSource sends for them. WebSocket clients, and EventSource on
+// its first connect, pass the lastEventId query parameter instead.
+package relay
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golift.io/securityspy/v2"
+)
+
+const (
+	// DefaultBuffer is how many events each client may fall behind before it is disconnected.
+	DefaultBuffer = 100
+	// DefaultHistory is how many recent events are kept for clients that resume.
+	DefaultHistory = 1000
+	// DefaultKeepAlive is how often idle connections get a comment or ping.
+	DefaultKeepAlive = 15 * time.Second
+)
+
+// Config is optional input for New.
+type Config struct {
+	// Buffer is how many events each client may fall behind. Clients that fall further
+	// behind are disconnected, and may reconnect and resume. 0 uses DefaultBuffer.
+	Buffer int
+	// History is how many recent events are kept for resuming clients. 0 uses DefaultHistory.
+	History int
+	// KeepAlive is how often idle connections get a keep-alive. 0 uses DefaultKeepAlive.
+	KeepAlive time.Duration
+	// WebSocket accepts WebSocket upgrades on the same handler.
+	WebSocket bool
+	// CheckOrigin accepts or rejects a WebSocket upgrade. Nil rejects requests
+	// with an Origin header whose host is not the request's Host.
+	CheckOrigin func(req *http.Request) bool
+}
+
+// Relay is an http.Handler that serves events to clients. Create one with New.
+type Relay struct {
+	config   Config
+	upgrader websocket.Upgrader
+	mu       sync.Mutex // Protects the fields below.
+	history  []*message
+	clients  map[*client]struct{}
+	closed   bool
+}
+
+// message is an event ready to send.
+type message struct {
+	id     int
+	record securityspy.EventRecord
+	data   []byte // JSON EventRecord.
+}
+
+// client is one connected HTTP client.
+type client struct {
+	filter filter
+	send   chan *message
+	done   chan struct{} // Closed to disconnect the client.
+	once   sync.Once
+}
+
+// filter is the event selection from a client's query parameters. Empty fields match everything.
+type filter struct {
+	cameras []int
+	names   []string
+	types   []securityspy.EventType
+}
+
+// New returns a Relay. Config may be nil.
+func New(config *Config) *Relay {
+	if config == nil {
+		config = &Config{}
+	}
+
+	rly := &Relay{config: *config, clients: make(map[*client]struct{})}
+
+	if rly.config.Buffer <= 0 {
+		rly.config.Buffer = DefaultBuffer
+	}
+
+	if rly.config.History <= 0 {
+		rly.config.History = DefaultHistory
+	}
+
+	if rly.config.KeepAlive <= 0 {
+		rly.config.KeepAlive = DefaultKeepAlive
+	}
+
+	rly.upgrader.CheckOrigin = rly.config.CheckOrigin
+
+	return rly
+}
+
+// BindEvents publishes every event from events to the relay's clients, in stream order.
+// Call this before Events.Watch. Stop publishing with Binding.Unbind.
+func (r *Relay) BindEvents(events *securityspy.Events) *securityspy.Binding {
+	return events.BindFuncConfig(securityspy.EventAllEvents, r.Publish, &securityspy.BindConfig{Order: securityspy.OrderGlobal})
+}
+
+// Publish sends an event to every client whose filter matches, and keeps it for
+// resuming clients. BindEvents feeds every event here. Keep-alive (NULL) events are
+// not relayed; the relay sends its own. Clients that are too far behind are disconnected.
+func (r *Relay) Publish(event securityspy.Event) {
+	if event.Type == securityspy.EventKeepAlive {
+		return
+	}
+
+	msg := &message{id: event.ID, record: event.Record()}
+
+	var err error
+	if msg.data, err = json.Marshal(msg.record); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.history = append(r.history, msg)
+	if extra := len(r.history) - r.config.History; extra > 0 {
+		r.history = slices.Delete(r.history, 0, extra)
+	}
+
+	for client := range r.clients {
+		if !client.filter.match(msg) {
+			continue
+		}
+
+		select {
+		case client.send <- msg:
+		default:
+			client.disconnect()
+			delete(r.clients, client)
+		}
+	}
+}
+
+// Clients returns the number of connected clients.
+func (r *Relay) Clients() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.clients)
+}
+
+// Close disconnects every client. Later requests get 503 Service Unavailable.
+// Call this before http.Server.Shutdown, which does not end streaming responses.
+func (r *Relay) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	for client := range r.clients {
+		client.disconnect()
+		delete(r.clients, client)
+	}
+}
+
+// ServeHTTP streams events to the client as Server-Sent Events, or WebSocket
+// messages if the request is a WebSocket upgrade and Config.WebSocket is set.
+func (r *Relay) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		resp.Header().Set("Allow", http.MethodGet)
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	isWebSocket := websocket.IsWebSocketUpgrade(req)
+	if isWebSocket && !r.config.WebSocket {
+		http.Error(resp, "websocket not enabled", http.StatusBadRequest)
+		return
+	}
+
+	var conn *websocket.Conn
+
+	if isWebSocket {
+		var err error
+		if conn, err = r.upgrader.Upgrade(resp, req, nil); err != nil {
+			return // Upgrade wrote the error response.
+		}
+		defer conn.Close()
+	}
+
+	client, backlog, ok := r.connect(req)
+	if !ok {
+		if conn != nil {
+			_ = conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "relay closed"))
+		} else {
+			http.Error(resp, "relay closed", http.StatusServiceUnavailable)
+		}
+
+		return
+	}
+	defer r.disconnect(client)
+
+	if conn != nil {
+		r.serveWebSocket(conn, client, backlog)
+	} else {
+		r.serveSSE(resp, req, client, backlog)
+	}
+}
+
+// connect registers a client, and returns the events it missed if it is resuming.
+// Registering and reading the history under one lock means no event is missed or sent twice.
+func (r *Relay) connect(req *http.Request) (*client, []*message, bool) {
+	newClient := &client{
+		filter: parseFilter(req),
+		send:   make(chan *message, r.config.Buffer),
+		done:   make(chan struct{}),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, nil, false
+	}
+
+	r.clients[newClient] = struct{}{}
+
+	lastID := req.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = req.URL.Query().Get("lastEventId")
+	}
+
+	last, err := strconv.Atoi(lastID)
+	if err != nil {
+		return newClient, nil, true
+	}
+
+	// Event numbers restart with SecuritySpy, so use the newest match. With no
+	// match the client missed an unknown number of events, so it gets them all.
+	start := 0
+
+	for idx, msg := range slices.Backward(r.history) {
+		if msg.id == last {
+			start = idx + 1
+			break
+		}
+	}
+
+	backlog := []*message{}
+
+	for _, msg := range r.history[start:] {
+		if newClient.filter.match(msg) {
+			backlog = append(backlog, msg)
+		}
+	}
+
+	return newClient, backlog, true
+}
+
+// disconnect removes a client when its request ends.
+func (r *Relay) disconnect(client *client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	client.disconnect()
+	delete(r.clients, client)
+}
+
+func (c *client) disconnect() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// parseFilter reads the camera and type query parameters.
+func parseFilter(req *http.Request) filter {
+	var clientFilter filter
+
+	query := req.URL.Query()
+
+	for _, value := range splitParams(query["camera"]) {
+		if num, err := strconv.Atoi(value); err == nil {
+			clientFilter.cameras = append(clientFilter.cameras, num)
+		} else {
+			clientFilter.names = append(clientFilter.names, value)
+		}
+	}
+
+	for _, value := range splitParams(query["type"]) {
+		clientFilter.types = append(clientFilter.types, securityspy.EventType(strings.ToUpper(value)))
+	}
+
+	return clientFilter
+}
+
+// splitParams splits comma separated query values and drops empty ones.
+func splitParams(values []string) []string {
+	split := []string{}
+
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				split = append(split, part)
+			}
+		}
+	}
+
+	return split
+}
+
+// match returns true if the message passes the camera and type filters.
+// Events without a camera do not match a camera filter.
+func (f *filter) match(msg *message) bool {
+	if len(f.types) > 0 && !slices.Contains(f.types, msg.record.Type) {
+		return false
+	}
+
+	if len(f.cameras) == 0 && len(f.names) == 0 {
+		return true
+	}
+
+	return slices.Contains(f.cameras, msg.record.Camera) ||
+		(msg.record.CameraName != "" && slices.Contains(f.names, msg.record.CameraName))
+}
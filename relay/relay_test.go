@@ -0,0 +1,166 @@
+package relay_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/relay"
+)
+
+func event(id, camera int, eventType securityspy.EventType) securityspy.Event {
+	event := securityspy.Event{ID: id, Type: eventType, Time: time.Now(), When: time.Now()}
+	if camera > 0 {
+		event.Camera = &securityspy.Camera{Number: camera, Name: map[int]string{2: "Porch", 3: "Door"}[camera]}
+	}
+
+	return event
+}
+
+// readSSE reads one Server-Sent Event and returns its id and decoded data.
+func readSSE(t *testing.T, reader *bufio.Reader) (string, securityspy.EventRecord) {
+	t.Helper()
+
+	var (
+		id     string
+		record securityspy.EventRecord
+	)
+
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		switch line = strings.TrimSuffix(line, "\n"); {
+		case line == "":
+			return id, record
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &record))
+		}
+	}
+}
+
+func connectSSE(t *testing.T, rly *relay.Relay, url string, lastID string) *bufio.Reader {
+	t.Helper()
+
+	clients := rly.Clients()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Eventually(t, func() bool { return rly.Clients() == clients+1 }, time.Second, time.Millisecond)
+
+	return bufio.NewReader(resp.Body)
+}
+
+func TestRelaySSE(t *testing.T) {
+	t.Parallel()
+
+	rly := relay.New(nil)
+	srv := httptest.NewServer(rly)
+	t.Cleanup(srv.Close)
+
+	door := connectSSE(t, rly, srv.URL+"?camera=Door&type=trigger_m", "")
+
+	rly.Publish(event(1, 3, securityspy.EventTriggerMotion))
+	rly.Publish(event(2, 2, securityspy.EventTriggerMotion))
+	rly.Publish(event(3, 3, securityspy.EventOnline))
+	rly.Publish(event(-10000, 0, securityspy.EventStreamDisconnect))
+	rly.Publish(event(4, -1, securityspy.EventKeepAlive))
+	rly.Publish(event(5, 3, securityspy.EventTriggerMotion))
+
+	id, record := readSSE(t, door)
+	require.Equal(t, "1", id)
+	require.Equal(t, "Door", record.CameraName)
+	require.Equal(t, securityspy.EventTriggerMotion, record.Type)
+
+	id, _ = readSSE(t, door)
+	require.Equal(t, "5", id)
+
+	// A reconnect with Last-Event-ID gets the matching events it missed, then live ones.
+	resumed := connectSSE(t, rly, srv.URL+"?camera=3,2", "2")
+	rly.Publish(event(6, 2, securityspy.EventOffline))
+
+	for _, want := range []string{"3", "5", "6"} {
+		id, _ = readSSE(t, resumed)
+		require.Equal(t, want, id)
+	}
+
+	// Library events have no id, so they do not move Last-Event-ID.
+	everything := connectSSE(t, rly, srv.URL, "3")
+	id, record = readSSE(t, everything)
+	require.Empty(t, id)
+	require.Equal(t, securityspy.EventStreamDisconnect, record.Type)
+	require.Equal(t, -1, record.Camera)
+
+	rly.Close()
+	require.Zero(t, rly.Clients())
+
+	_, err := door.ReadString('\n')
+	require.Error(t, err, "Close ends every stream")
+
+	resp, err := http.Get(srv.URL) //nolint:noctx
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestRelayWebSocket(t *testing.T) {
+	t.Parallel()
+
+	plain := httptest.NewServer(relay.New(nil))
+	t.Cleanup(plain.Close)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(plain.URL, "http"), nil)
+	require.Error(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode, "WebSocket is off by default")
+
+	rly := relay.New(&relay.Config{WebSocket: true})
+	srv := httptest.NewServer(rly)
+	t.Cleanup(srv.Close)
+
+	rly.Publish(event(7, 3, securityspy.EventTriggerMotion))
+	rly.Publish(event(8, 3, securityspy.EventOnline))
+	rly.Publish(event(9, 3, securityspy.EventTriggerMotion))
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?type=TRIGGER_M&lastEventId=7", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	t.Cleanup(func() { _ = conn.Close() })
+
+	require.Eventually(t, func() bool { return rly.Clients() == 1 }, time.Second, time.Millisecond)
+	rly.Publish(event(10, 2, securityspy.EventTriggerMotion))
+
+	for _, want := range []int{9, 10} {
+		var record securityspy.EventRecord
+		require.NoError(t, conn.ReadJSON(&record))
+		require.Equal(t, want, record.ID)
+	}
+
+	require.NoError(t, conn.Close())
+	require.Eventually(t, func() bool { return rly.Clients() == 0 }, time.Second, time.Millisecond)
+
+	header := http.Header{"Origin": {"https://evil.example"}}
+	_, resp, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), header)
+	require.Error(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusForbidden, resp.StatusCode, "other origins are rejected")
+}
@@ -0,0 +1,117 @@
+package relay
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// readLimit caps the size of messages from WebSocket clients, which have nothing to send.
+const readLimit = 4096
+
+// serveSSE writes events as Server-Sent Events until the request ends or the client is disconnected.
+func (r *Relay) serveSSE(resp http.ResponseWriter, req *http.Request, client *client, backlog []*message) {
+	ctrl := http.NewResponseController(resp)
+	_ = ctrl.SetWriteDeadline(time.Time{}) // The response lasts as long as the client stays.
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("X-Accel-Buffering", "no") // Stops nginx from buffering the stream.
+	resp.WriteHeader(http.StatusOK)
+
+	var buf bytes.Buffer
+
+	for _, msg := range backlog {
+		writeSSE(&buf, msg)
+	}
+
+	if _, err := resp.Write(buf.Bytes()); err != nil || ctrl.Flush() != nil {
+		return
+	}
+
+	ticker := time.NewTicker(r.config.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		buf.Reset()
+
+		select {
+		case <-req.Context().Done():
+			return
+		case <-client.done:
+			return
+		case <-ticker.C:
+			buf.WriteString(": keep-alive\n\n")
+		case msg := <-client.send:
+			writeSSE(&buf, msg)
+		}
+
+		if _, err := resp.Write(buf.Bytes()); err != nil || ctrl.Flush() != nil {
+			return
+		}
+	}
+}
+
+// writeSSE formats one event. Library events have negative numbers and no id,
+// so they do not change the client's Last-Event-ID.
+func writeSSE(buf *bytes.Buffer, msg *message) {
+	if msg.id >= 0 {
+		buf.WriteString("id: " + strconv.Itoa(msg.id) + "\n")
+	}
+
+	buf.WriteString("data: ")
+	buf.Write(msg.data)
+	buf.WriteString("\n\n")
+}
+
+// serveWebSocket writes events as text messages until the client goes away or is disconnected.
+func (r *Relay) serveWebSocket(conn *websocket.Conn, client *client, backlog []*message) {
+	gone := make(chan struct{})
+
+	go func() {
+		defer close(gone)
+
+		conn.SetReadLimit(readLimit)
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return // Closed by the client, or the connection failed.
+			}
+		}
+	}()
+
+	write := func(msgType int, data []byte) bool {
+		_ = conn.SetWriteDeadline(time.Now().Add(r.config.KeepAlive))
+		return conn.WriteMessage(msgType, data) == nil
+	}
+
+	for _, msg := range backlog {
+		if !write(websocket.TextMessage, msg.data) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(r.config.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case <-client.done:
+			write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+			return
+		case <-ticker.C:
+			if !write(websocket.PingMessage, nil) {
+				return
+			}
+		case msg := <-client.send:
+			if !write(websocket.TextMessage, msg.data) {
+				return
+			}
+		}
+	}
+}
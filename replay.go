@@ -0,0 +1,105 @@
+package securityspy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Replay reads raw event-stream text, like a saved ++eventStream capture, and delivers
+// each event to the bound callbacks and channels as if it came from the live stream.
+// Lines may end with CR, LF or CRLF. Each event's Time is set to its recorded time, so an
+// IncidentTracker splits a recording into the same incidents at every speed. Sequence
+// numbers are not checked. Watch does not need to be running.
+// With config.Speed, Replay sleeps between events for the time between their timestamps.
+// Unless config.NoWait is set, it waits for the bindings to finish with each event before
+// sending the next one, so a channel with no reader stalls the replay until ctx ends.
+// Returns the number of events delivered. Config may be nil.
+func (e *Events) Replay(ctx context.Context, reader io.Reader, config *ReplayConfig) (int, error) {
+	if config == nil {
+		config = &ReplayConfig{}
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(scanLines)
+
+	var (
+		count int
+		last  time.Time
+	)
+
+	for scanner.Scan() {
+		text := scanner.Text()
+		if strings.Count(text, " ") <= 2 { //nolint:mnd // same as the live stream.
+			continue
+		}
+
+		event := e.UnmarshalEvent(text)
+
+		if err := replayWait(ctx, last, event.When, config.Speed); err != nil {
+			return count, err
+		}
+
+		last = event.When
+		event.Time = event.When
+		e.dispatch(ctx, event)
+		count++
+
+		if config.NoWait {
+			continue
+		}
+
+		if err := e.drain(ctx, replayPoll); err != nil {
+			return count, fmt.Errorf("replaying events: %w", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("replaying events: %w", err)
+	}
+
+	return count, nil
+}
+
+// replayWait sleeps for the time between two recorded events, divided by speed.
+func replayWait(ctx context.Context, last, next time.Time, speed float64) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("replaying events: %w", err)
+	}
+
+	if speed <= 0 || last.IsZero() || !next.After(last) {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(float64(next.Sub(last)) / speed))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("replaying events: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// scanLines is a bufio.SplitFunc for saved event streams. Lines end with CR, LF or CRLF.
+// Empty lines are returned too; Replay skips them with other short lines.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if idx := bytes.IndexAny(data, "\r\n"); idx >= 0 {
+		return idx + 1, data[:idx], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
@@ -0,0 +1,137 @@
+package securityspy_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	var (
+		mu   sync.Mutex
+		seen []int
+	)
+
+	sspy.Events.BindFunc(securityspy.EventAllEvents, func(event securityspy.Event) {
+		time.Sleep(time.Millisecond) // Later events would pass this one without Replay waiting.
+		mu.Lock()
+		defer mu.Unlock()
+
+		seen = append(seen, event.ID)
+	})
+
+	triggers := make(chan securityspy.Event, 1)
+	sspy.Events.BindChan(securityspy.EventTriggerMotion, triggers)
+
+	capture, err := os.Open("testdata/incident.txt")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = capture.Close() })
+
+	start := time.Now()
+	count, err := sspy.Events.Replay(context.Background(), capture, &securityspy.ReplayConfig{Speed: 100})
+	require.NoError(t, err)
+	require.Equal(t, 6, count)
+	require.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond, "6 recorded seconds at 100x")
+	require.Equal(t, []int{41, 42, 43, 44, 45, 46}, seen, "callbacks run in recorded order")
+
+	trigger := <-triggers
+	require.Equal(t, "Door", trigger.Camera.Name)
+	require.Contains(t, trigger.Reasons, securityspy.TriggerByHumanDetection)
+	require.Equal(t, trigger.When, trigger.Time, "replayed events are received at their recorded time")
+	require.Equal(t, 3, trigger.When.Second())
+	require.Equal(t, securityspy.StreamStopped, sspy.Events.State(), "the live stream is not touched")
+}
+
+func TestReplayCanceled(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	stream := "20261015201500 1 3 ONLINE\n20261015211500 2 3 OFFLINE\n"
+	count, err := sspy.Events.Replay(ctx, strings.NewReader(stream), &securityspy.ReplayConfig{Speed: 1})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 1, count, "the second event is an hour later")
+
+	count, err = sspy.Events.Replay(context.Background(), strings.NewReader(stream), nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, count, "no Speed replays without waiting")
+}
+
+func TestReplayIncidents(t *testing.T) {
+	t.Parallel()
+
+	// The capture has a 2 second gap on camera 3, longer than IdleTimeout, so the same
+	// motion splits into two incidents however fast it is replayed.
+	want := []string{
+		"2 idle timeout 1",
+		"3 file 3",
+		"3 idle timeout 2",
+	}
+
+	for _, speed := range []float64{0, 10, 1} {
+		t.Run(fmt.Sprint(speed), func(t *testing.T) {
+			t.Parallel()
+
+			fake := securityspytest.NewServer()
+			t.Cleanup(fake.Close)
+
+			sspy, err := securityspy.New(fake.Config())
+			require.NoError(t, err)
+
+			tracker := securityspy.NewIncidentTracker(sspy.Events, &securityspy.IncidentConfig{
+				IdleTimeout: 1500 * time.Millisecond,
+				FileWait:    1500 * time.Millisecond,
+			})
+			t.Cleanup(tracker.Stop)
+
+			incidents := make(chan securityspy.Incident, 10)
+			tracker.BindChan(incidents)
+
+			capture, err := os.Open("testdata/incidents.txt")
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = capture.Close() })
+
+			count, err := sspy.Events.Replay(context.Background(), capture, &securityspy.ReplayConfig{Speed: speed})
+			require.NoError(t, err)
+			require.Equal(t, 6, count)
+
+			got := make([]string, 0, len(want))
+
+			for range want {
+				select {
+				case incident := <-incidents:
+					got = append(got, fmt.Sprint(incident.Camera.Number, " ", incident.EndedBy, " ", len(incident.Events)))
+				case <-time.After(2 * time.Second):
+					t.Fatal("no incident")
+				}
+			}
+
+			slices.Sort(got)
+			require.Equal(t, want, got)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package securityspy
+
+import "time"
+
+// replayPoll is how often Replay checks whether the bindings finished with an event.
+const replayPoll = time.Millisecond
+
+// ReplayConfig is optional input for Events.Replay.
+type ReplayConfig struct {
+	// Speed scales the time between events, taken from their recorded timestamps.
+	// 1 replays in real time, 60 replays an hour in a minute, and 0 replays without waiting.
+	Speed float64
+	// NoWait dispatches the next event without waiting for the bindings to finish
+	// with the last one. Callbacks may then see events out of order.
+	NoWait bool
+}
@@ -12,6 +12,7 @@ import (
 	"golift.io/securityspy/v2/server"
 )
 
+// maxCallbackWorkers is how many OrderNone callbacks each binding runs at once.
 const maxCallbackWorkers = 32
 
 // New returns an interface to interact with SecuritySpy.
@@ -33,15 +34,19 @@ func NewMust(config *server.Config) *Server {
 		config.Password = base64.URLEncoding.EncodeToString([]byte(config.Username + ":" + config.Password))
 	}
 
+	if config.Client == nil {
+		config.Client = config.HTTPClient()
+	}
+
 	// Assign all the sub-interface structs.
 	secspyServer := &Server{Config: config, Encoder: DefaultEncoder, Info: &ServerInfo{}}
 	secspyServer.Files = &Files{server: secspyServer}
+	secspyServer.Poller = &Poller{server: secspyServer}
 	secspyServer.Cameras = &Cameras{server: secspyServer}
+	secspyServer.snapshot.Store(&Snapshot{Info: secspyServer.Info, Cameras: secspyServer.Cameras})
 	secspyServer.Events = &Events{
-		server:      secspyServer,
-		eventBinds:  make(map[EventType][]func(Event)),
-		eventChans:  make(map[EventType][]chan Event),
-		callbackSem: make(chan struct{}, maxCallbackWorkers),
+		server:   secspyServer,
+		bindings: make(map[EventType][]*Binding),
 	}
 
 	return secspyServer
@@ -49,14 +54,17 @@ func NewMust(config *server.Config) *Server {
 
 // Refresh gets fresh camera and serverInfo data from SecuritySpy,
 // run this after every action to keep the data pool up to date.
-// This is not at all thread safe. Do not run this if other methods
-// may run in a different go routine.
+// Refresh publishes a new Snapshot; it is safe to call while other
+// goroutines read Snapshot(), but not while they read the deprecated
+// Info, Cameras or Groups fields directly.
 func (s *Server) Refresh() error {
 	return s.RefreshContext(context.Background())
 }
 
 // RefreshContext gets fresh camera and serverInfo data from SecuritySpy with context support.
-func (s *Server) RefreshContext(ctx context.Context) error { //nolint:cyclop // schedule name wiring
+// Concurrent refreshes run one at a time. The previous snapshot is kept if the request fails.
+// Differences from the previous snapshot are sent to Events bindings while Watch is running.
+func (s *Server) RefreshContext(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -66,51 +74,28 @@ func (s *Server) RefreshContext(ctx context.Context) error { //nolint:cyclop //
 		return fmt.Errorf("getting systemInfo: %w", err)
 	}
 
-	s.Info = sysInfo.Server
-	if s.Info == nil {
-		s.Info = &ServerInfo{}
-	}
+	s.publish(&sysInfo)
 
-	s.Cameras = &Cameras{cameras: sysInfo.cameras(), server: s}
-	s.Groups = sysInfo.GroupList.Groups
-	s.Info.Refreshed = time.Now()
-	// Point all the unmarshalled data into an exported struct. Better-formatted data.
-	s.Info.ServerSchedules = sysInfo.schedules()
-	s.Info.SchedulePresets = sysInfo.schedulePresets()
-	s.Info.ScheduleOverrides = sysInfo.scheduleOverrides()
-
-	for idx, cam := range s.Cameras.cameras {
-		s.Cameras.cameras[idx].server = s
-		if s.Cameras.cameras[idx].PTZ != nil {
-			s.Cameras.cameras[idx].PTZ.camera = s.Cameras.cameras[idx]
-		}
-		// Fill in the missing schedule names (all we have are IDs, so fetch the names from systemInfo)
-		if name, ok := s.Info.ServerSchedules[cam.ScheduleIDA.ID]; ok {
-			s.Cameras.cameras[idx].ScheduleIDA.Name = name
-		}
-
-		if name, ok := s.Info.ServerSchedules[cam.ScheduleIDCC.ID]; ok {
-			s.Cameras.cameras[idx].ScheduleIDCC.Name = name
-		}
-
-		if name, ok := s.Info.ServerSchedules[cam.ScheduleIDMC.ID]; ok {
-			s.Cameras.cameras[idx].ScheduleIDMC.Name = name
-		}
-
-		if name, ok := s.Info.ScheduleOverrides[cam.ScheduleOverrideA.ID]; ok {
-			s.Cameras.cameras[idx].ScheduleOverrideA.Name = name
-		}
-
-		if name, ok := s.Info.ScheduleOverrides[cam.ScheduleOverrideCC.ID]; ok {
-			s.Cameras.cameras[idx].ScheduleOverrideCC.Name = name
-		}
-
-		if name, ok := s.Info.ScheduleOverrides[cam.ScheduleOverrideMC.ID]; ok {
-			s.Cameras.cameras[idx].ScheduleOverrideMC.Name = name
-		}
+	return nil
+}
+
+// publish builds the next snapshot from a systemInfo reply, diffs it against the current
+// one and swaps it in. Call with s.mu held: reading the current snapshot and storing the
+// next one must not interleave with another refresh, or two would share a Generation
+// and one set of Changes would be lost.
+func (s *Server) publish(sysInfo *systemInfo) {
+	prev := s.Snapshot()
+	snap := s.newSnapshot(sysInfo, prev.Generation+1)
+	snap.Info.Refreshed = time.Now()
+
+	if prev.Generation > 0 { // Everything is new on the first refresh; that is not a change.
+		snap.Changes = prev.Diff(snap)
 	}
 
-	return nil
+	// The deprecated fields are still set for old callers; nothing in this package reads them.
+	s.Info, s.Cameras, s.Groups = snap.Info, snap.Cameras, snap.Groups
+	s.snapshot.Store(snap)
+	s.Events.publishChanges(snap.Changes)
 }
 
 // GetScripts fetches and returns the list of script files.
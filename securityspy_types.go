@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golift.io/securityspy/v2/server"
@@ -13,9 +14,9 @@ import (
 // Server is the main interface for this library.
 // Contains sub-interfaces for cameras, ptz, files & events
 // This is provided in exchange for a url, username and password.
-// If your app calls Refresh(), it is your duty to use Rlock() on
-// this struct if there's a chance you may call methods while
-// Refresh() is running.
+// Cameras, Groups and Info are replaced by every Refresh() without locking,
+// so they are deprecated: read inventory from Snapshot() instead; snapshots
+// are immutable and swapped atomically.
 type Server struct {
 	*server.Config
 
@@ -23,12 +24,26 @@ type Server struct {
 	//
 	// Deprecated: unused; video capture is pure Go and does not shell out to ffmpeg.
 	Encoder string
-	Files   *Files       // Files interface.
-	Events  *Events      // Events interface.
-	Cameras *Cameras     // Cameras & PTZ interfaces.
-	Groups  []*Group     // Camera groups from systemInfo (v6+).
-	Info    *ServerInfo  // ServerInfo struct (no methods).
-	mu      sync.RWMutex // Lock for Refresh().
+	Files   *Files  // Files interface.
+	Events  *Events // Events interface.
+	Poller  *Poller // Background refresh and health.
+	// Cameras & PTZ interfaces.
+	//
+	// Deprecated: Refresh replaces this without locking; use Snapshot().Cameras.
+	Cameras *Cameras
+	// Camera groups from systemInfo (v6+).
+	//
+	// Deprecated: Refresh replaces this without locking; use Snapshot().Groups.
+	Groups []*Group
+	// ServerInfo struct (no methods).
+	//
+	// Deprecated: Refresh replaces this without locking; use Snapshot().Info.
+	Info *ServerInfo
+
+	mu       sync.Mutex               // Serializes Refresh(), from the request to publishing the snapshot.
+	snapshot atomic.Pointer[Snapshot] // Current inventory.
+	capsMu   sync.Mutex               // Protects notFound.
+	notFound map[string]string        // Endpoints that returned 404, and the server version they did it on.
 }
 
 // Group is a named camera group from ++systemInfo (v6+).
@@ -65,7 +80,7 @@ func (g *Group) CameraNumbers() []int {
 }
 
 // ServerInfo represents all the SecuritySpy server's information.
-// This becomes available as server.Info.
+// This becomes available as Snapshot().Info.
 type ServerInfo struct {
 	Name             string    `xml:"name"`               // SecuritySpy
 	Version          string    `xml:"version"`            // 6.20
@@ -0,0 +1,122 @@
+package securityspytest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamBuffer is how many lines each event stream client may fall behind before lines are dropped.
+const streamBuffer = 1000
+
+// PushEvent writes an event line to every connected ++eventStream client.
+// The time stamp and event number are filled in. Use -1 for events that do not
+// belong to a camera (written as X). Returns the event number used.
+//
+//	fake.PushEvent(3, "TRIGGER_M", "9")
+//	fake.PushEvent(3, "CLASSIFY", "HUMAN", "99", "VEHICLE", "0")
+func (s *Server) PushEvent(cameraNum int, event string, info ...string) int {
+	s.mu.Lock()
+	s.eventNum++
+	num := s.eventNum
+	s.mu.Unlock()
+
+	parts := append([]string{time.Now().Format(EventTimeFormat), strconv.Itoa(num), cameraString(cameraNum), event}, info...)
+	s.PushLine(strings.Join(parts, " "))
+
+	return num
+}
+
+// KeepAlive writes a NULL heartbeat event to every connected ++eventStream client.
+func (s *Server) KeepAlive() int {
+	return s.PushEvent(-1, "NULL")
+}
+
+// PushLine writes a raw line, without its terminator, to every connected ++eventStream client.
+// Use this to send malformed or hand-numbered events.
+func (s *Server) PushLine(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for stream := range s.streams {
+		select {
+		case stream <- line:
+		default:
+		}
+	}
+}
+
+// SetEventNumber sets the number the next PushEvent continues from.
+// Use this to emulate gaps or a server restart.
+func (s *Server) SetEventNumber(last int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.eventNum = last
+}
+
+// EventStreamClients returns the number of connected ++eventStream clients.
+func (s *Server) EventStreamClients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.streams)
+}
+
+// CloseEventStreams disconnects every ++eventStream client.
+func (s *Server) CloseEventStreams() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for stream := range s.streams {
+		close(stream)
+		delete(s.streams, stream)
+	}
+}
+
+// serveEventStream holds the connection open and writes pushed lines, CR-terminated.
+func (s *Server) serveEventStream(resp http.ResponseWriter, req *http.Request) {
+	flusher, _ := resp.(http.Flusher)
+	stream := make(chan string, streamBuffer)
+
+	s.mu.Lock()
+	s.streams[stream] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.streams[stream]; ok {
+			close(stream)
+			delete(s.streams, stream)
+		}
+	}()
+
+	resp.Header().Set("Content-Type", "text/plain")
+	resp.WriteHeader(http.StatusOK)
+
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case line, ok := <-stream:
+			if !ok {
+				return
+			}
+
+			if _, err := resp.Write([]byte(line + "\r")); err != nil {
+				return
+			}
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
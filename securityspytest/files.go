@@ -0,0 +1,183 @@
+package securityspytest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	downloadDateFormat = "2006-01-02"
+	fileDateFormat     = "01-02-2006 15-04-05"
+)
+
+// Title returns the file name SecuritySpy would save this file as,
+// ie. "01-18-2019 10-17-53 M Porch.m4v".
+func (f *File) Title(cameraName string) string {
+	ext := ".m4v"
+	if f.Capture == CaptureImage {
+		ext = ".jpg"
+	}
+
+	return f.Time.Format(fileDateFormat) + " " + string(f.Capture) + " " + cameraName + ext
+}
+
+// HREF returns the ++getfile link for this file.
+func (f *File) HREF(cameraName string) string {
+	return "++getfile/" + strconv.Itoa(f.CameraNum) + "/" + f.Time.Format(downloadDateFormat) + "/" +
+		url.QueryEscape(f.Title(cameraName))
+}
+
+func (f *File) contentType() string {
+	if f.Capture == CaptureImage {
+		return "image/jpeg"
+	}
+
+	return "video/quicktime"
+}
+
+func (f *File) data() []byte {
+	if len(f.Data) > 0 {
+		return f.Data
+	}
+
+	return []byte("fake " + string(f.Capture) + " file for camera " + strconv.Itoa(f.CameraNum))
+}
+
+// matches reports whether a file is selected by a ++download query.
+func (f *File) matches(query url.Values, cameras []int) bool {
+	if len(cameras) > 0 && !slices.Contains(cameras, f.CameraNum) {
+		return false
+	}
+
+	switch f.Capture {
+	case CaptureMotion:
+		if query.Get("mcFilesCheck") != "1" {
+			return false
+		}
+	case CaptureContinuous:
+		if query.Get("ccFilesCheck") != "1" {
+			return false
+		}
+	case CaptureImage:
+		if query.Get("imageFilesCheck") != "1" {
+			return false
+		}
+	}
+
+	day := f.Time.Format(downloadDateFormat)
+	if from := query.Get("date1"); from != "" && day < from {
+		return false
+	}
+
+	if to := query.Get("date2"); to != "" && day > to {
+		return false
+	}
+
+	return true
+}
+
+// serveDownload renders a ++download feed page and a continuation when more files remain.
+func (s *Server) serveDownload(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	cameras := queryInts(query, "cameraNum")
+	offset := parseContinuation(query.Get("continuation"))
+
+	results := atoi(query.Get("results"))
+	if results <= 0 {
+		results = defaultResults
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pageSize > 0 && s.pageSize < results {
+		results = s.pageSize
+	}
+
+	feed := fileFeedXML{Title: "Downloads", GmtOffset: s.info.GmtOffset}
+	matched := 0
+
+	for idx := range s.files {
+		file := &s.files[idx]
+		if !file.matches(query, cameras) {
+			continue
+		}
+
+		if matched++; matched <= offset {
+			continue
+		}
+
+		if len(feed.Entries) == results {
+			feed.Continuation = fmt.Sprintf("%016X", offset+results)
+			break
+		}
+
+		feed.Entries = append(feed.Entries, s.fileEntry(file))
+	}
+
+	writeXML(resp, feed)
+}
+
+// fileEntry renders one ++download entry. Call with the lock held.
+func (s *Server) fileEntry(file *File) fileEntryXML {
+	name := ""
+	if camera := s.cameras[file.CameraNum]; camera != nil {
+		name = camera.Name
+	}
+
+	entry := fileEntryXML{
+		Title:     file.Title(name),
+		Updated:   file.Time.UTC().Format(time.RFC3339),
+		CameraNum: file.CameraNum,
+	}
+	entry.Link.Rel = "alternate"
+	entry.Link.Type = file.contentType()
+	entry.Link.Length = len(file.data())
+	entry.Link.HREF = file.HREF(name)
+
+	return entry
+}
+
+// serveFile handles ++getfile, ++getfilehb and ++getfilelb: /++getfile/<cam>/<date>/<name>.
+func (s *Server) serveFile(resp http.ResponseWriter, req *http.Request) {
+	const pathParts = 4
+
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.EscapedPath(), "/"), "/", pathParts)
+	if len(parts) != pathParts {
+		http.NotFound(resp, req)
+		return
+	}
+
+	title, err := url.QueryUnescape(parts[3])
+	if err != nil {
+		http.NotFound(resp, req)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for idx := range s.files {
+		file := &s.files[idx]
+
+		name := ""
+		if camera := s.cameras[file.CameraNum]; camera != nil {
+			name = camera.Name
+		}
+
+		if strconv.Itoa(file.CameraNum) == parts[1] && file.Time.Format(downloadDateFormat) == parts[2] &&
+			file.Title(name) == title {
+			resp.Header().Set("Content-Type", file.contentType())
+			_, _ = resp.Write(file.data())
+
+			return
+		}
+	}
+
+	http.NotFound(resp, req)
+}
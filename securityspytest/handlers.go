@@ -0,0 +1,494 @@
+package securityspytest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	replyOK         = "OK"
+	defaultResults  = 200
+	versionSettings = 6 // ++settings-* pages exist on v6+.
+)
+
+// Handle overrides the fake server's handler for an exact path, ie. "/++ssControlContinuous".
+// Use this to inject failures or emulate a server that lacks an endpoint.
+// Requests are still recorded. Pass a nil handler to restore the built-in behavior.
+func (s *Server) Handle(path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handler == nil {
+		delete(s.handlers, path)
+		return
+	}
+
+	s.handlers[path] = handler
+}
+
+// ServeHTTP records the request, checks authentication and routes it to a fake endpoint.
+func (s *Server) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPost {
+		_ = req.ParseForm()
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query(),
+		Form:   req.PostForm,
+		Time:   time.Now(),
+	})
+	auth := s.authBlob()
+	override := s.handlers[req.URL.Path]
+	s.mu.Unlock()
+
+	if auth != "" && req.URL.Query().Get("auth") != auth {
+		http.Error(resp, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if override != nil {
+		override(resp, req)
+		return
+	}
+
+	s.route(resp, req)
+}
+
+//nolint:cyclop // it's a router.
+func (s *Server) route(resp http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+
+	switch {
+	case path == "/++systemInfo":
+		s.serveSystemInfo(resp)
+	case path == "/++eventStream":
+		s.serveEventStream(resp, req)
+	case path == "/++image":
+		s.serveImage(resp, req)
+	case path == "/++cameramodes":
+		s.serveCameraModes(resp, req)
+	case path == "/++download":
+		s.serveDownload(resp, req)
+	case strings.HasPrefix(path, "/++getfile"):
+		s.serveFile(resp, req)
+	case path == "/++ssControlContinuous", path == "/++ssControlMotionCapture", path == "/++ssControlActions":
+		s.serveArm(resp, req)
+	case path == "/++ssSetSchedule", path == "/++setSchedule", path == "/++ssSetOverride":
+		s.serveSchedule(resp, req)
+	case path == "/++ssSetPreset":
+		s.servePreset(resp, req)
+	case path == "/++triggermd":
+		s.serveTrigger(resp, req)
+	case path == "/++ptz/command":
+		s.servePTZ(resp, req)
+	case path == "/++scripts":
+		s.serveNames(resp, func() []string { return s.scripts })
+	case path == "/++sounds":
+		s.serveNames(resp, func() []string { return s.sounds })
+	case strings.HasPrefix(path, "/++settings-"):
+		s.serveSettings(resp, req, strings.TrimPrefix(path, "/++settings-"))
+	default:
+		http.NotFound(resp, req)
+	}
+}
+
+func (s *Server) serveSystemInfo(resp http.ResponseWriter) {
+	s.mu.Lock()
+
+	info := systemInfoXML{Server: serverXML{
+		Name:           s.info.Name,
+		Version:        s.info.Version,
+		UUID:           s.info.UUID,
+		ServerName:     s.info.ServerName,
+		BonjourName:    s.info.BonjourName,
+		IP1:            s.info.IP1,
+		HTTPPort:       s.info.HTTPPort,
+		HTTPSPort:      s.info.HTTPSPort,
+		CurrentTime:    time.Now().Format(time.RFC3339),
+		GmtOffset:      s.info.GmtOffset,
+		DateFormat:     s.info.DateFormat,
+		TimeFormat:     s.info.TimeFormat,
+		CPUUsage:       s.info.CPUUsage,
+		MemoryPressure: s.info.MemoryPressure,
+		CertExpiryDays: s.info.CertExpiryDays,
+		CameraCount:    len(s.cameras),
+		NewVersion:     s.info.NewVersion,
+	}}
+
+	for _, num := range s.sortedCameras() {
+		info.Cameras = append(info.Cameras, s.cameras[num].xml())
+	}
+
+	for _, group := range s.groups {
+		nums := make([]string, len(group.Cameras))
+		for idx, num := range group.Cameras {
+			nums[idx] = strconv.Itoa(num)
+		}
+
+		info.Groups = append(info.Groups, groupXML{Number: group.Number, Name: group.Name, Cameras: strings.Join(nums, ",")})
+	}
+
+	info.Schedules = scheduleList(s.schedules)
+	info.Overrides = scheduleList(s.overrides)
+	info.Presets = scheduleList(s.presets)
+	s.mu.Unlock()
+
+	writeXML(resp, info)
+}
+
+func (s *Server) serveImage(resp http.ResponseWriter, req *http.Request) {
+	num, _ := strconv.Atoi(req.URL.Query().Get("cameraNum"))
+
+	s.mu.Lock()
+	camera := s.cameras[num]
+	data := s.jpeg
+	online := camera != nil && camera.Connected
+	s.mu.Unlock()
+
+	if !online {
+		http.NotFound(resp, req)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "image/jpeg")
+	_, _ = resp.Write(data)
+}
+
+func (s *Server) serveCameraModes(resp http.ResponseWriter, req *http.Request) {
+	num, _ := strconv.Atoi(req.URL.Query().Get("cameraNum"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	camera := s.cameras[num]
+	if camera == nil {
+		http.NotFound(resp, req)
+		return
+	}
+
+	_, _ = fmt.Fprintf(resp, "C:%s\r\nM:%s\r\nA:%s\r\n",
+		strings.ToUpper(armText(camera.ModeC)), strings.ToUpper(armText(camera.ModeM)), strings.ToUpper(armText(camera.ModeA)))
+}
+
+// serveArm handles the three ++ssControl* endpoints and emits ARM_* / DISARM_* events on change.
+func (s *Server) serveArm(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	num, _ := strconv.Atoi(query.Get("cameraNum"))
+	arm := query.Get("arm") == "1"
+
+	s.mu.Lock()
+
+	camera := s.cameras[num]
+	if camera == nil {
+		s.mu.Unlock()
+		_, _ = resp.Write([]byte("Error: invalid camera"))
+
+		return
+	}
+
+	var (
+		mode    *bool
+		suffix  string
+		changed bool
+	)
+
+	switch req.URL.Path {
+	case "/++ssControlContinuous":
+		mode, suffix = &camera.ModeC, "C"
+	case "/++ssControlMotionCapture":
+		mode, suffix = &camera.ModeM, "M"
+	default:
+		mode, suffix = &camera.ModeA, "A"
+	}
+
+	changed, *mode = *mode != arm, arm
+	s.mu.Unlock()
+
+	if changed {
+		event := "DISARM_" + suffix
+		if arm {
+			event = "ARM_" + suffix
+		}
+
+		s.PushEvent(num, event)
+	}
+
+	_, _ = resp.Write([]byte(replyOK))
+}
+
+// serveSchedule handles ++ssSetSchedule and ++ssSetOverride.
+func (s *Server) serveSchedule(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	num, _ := strconv.Atoi(query.Get("cameraNum"))
+	id, err := strconv.Atoi(query.Get("id"))
+	override := req.URL.Path == "/++ssSetOverride"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	known := s.schedules
+	if override {
+		known = s.overrides
+	}
+
+	camera := s.cameras[num]
+	if _, ok := known[id]; !ok || err != nil || camera == nil {
+		_, _ = resp.Write([]byte("Error: invalid schedule"))
+		return
+	}
+
+	mode := query.Get("mode")
+	targets := map[string][2]*int{
+		"C": {&camera.ScheduleCC, &camera.OverrideCC},
+		"M": {&camera.ScheduleMC, &camera.OverrideMC},
+		"A": {&camera.ScheduleA, &camera.OverrideA},
+	}
+
+	idx := 0
+	if override {
+		idx = 1
+	}
+
+	for key, target := range targets {
+		if mode == key || mode == "X" {
+			*target[idx] = id
+		}
+	}
+
+	_, _ = resp.Write([]byte(replyOK))
+}
+
+func (s *Server) servePreset(resp http.ResponseWriter, req *http.Request) {
+	id, err := strconv.Atoi(req.URL.Query().Get("id"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.presets[id]; !ok || err != nil {
+		_, _ = resp.Write([]byte("Error: invalid preset"))
+		return
+	}
+
+	s.preset = id
+	_, _ = resp.Write([]byte(replyOK))
+}
+
+// serveTrigger counts ++triggermd requests and emits TRIGGER_M with the web server reason (16).
+func (s *Server) serveTrigger(resp http.ResponseWriter, req *http.Request) {
+	num, _ := strconv.Atoi(req.URL.Query().Get("cameraNum"))
+
+	s.mu.Lock()
+
+	var triggered []int
+
+	for _, cam := range s.sortedCameras() {
+		if num == -1 || cam == num {
+			s.cameras[cam].Triggers++
+			triggered = append(triggered, cam)
+		}
+	}
+
+	s.mu.Unlock()
+
+	if len(triggered) == 0 {
+		_, _ = resp.Write([]byte("Error: invalid camera"))
+		return
+	}
+
+	for _, cam := range triggered {
+		s.PushEvent(cam, "TRIGGER_M", "16")
+	}
+
+	_, _ = resp.Write([]byte(replyOK))
+}
+
+func (s *Server) servePTZ(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	num, _ := strconv.Atoi(query.Get("cameraNum"))
+	command, err := strconv.Atoi(query.Get("command"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	camera := s.cameras[num]
+	if camera == nil || camera.PTZ == 0 || err != nil {
+		_, _ = resp.Write([]byte("Error: PTZ unavailable"))
+		return
+	}
+
+	camera.PTZCommands = append(camera.PTZCommands, command)
+	_, _ = resp.Write([]byte(replyOK))
+}
+
+func (s *Server) serveNames(resp http.ResponseWriter, names func() []string) {
+	s.mu.Lock()
+	list := namesXML{Names: slices.Clone(names())}
+	s.mu.Unlock()
+
+	writeXML(resp, list)
+}
+
+// serveSettings handles GET and POST for ++settings-<section>. These pages only exist on v6+.
+func (s *Server) serveSettings(resp http.ResponseWriter, req *http.Request, section string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if major, _, _ := strings.Cut(s.info.Version, "."); atoi(major) < versionSettings {
+		http.NotFound(resp, req)
+		return
+	}
+
+	values := s.settings[section]
+	if section == "cameras" {
+		num := atoi(req.FormValue("cameraNum"))
+		if s.camConfig[num] == nil {
+			s.camConfig[num] = map[string]string{}
+		}
+
+		values = s.camConfig[num]
+	} else if values == nil {
+		values = map[string]string{}
+		s.settings[section] = values
+	}
+
+	if req.Method == http.MethodPost {
+		for key := range req.PostForm {
+			if key != "cameraNum" {
+				values[key] = req.PostForm.Get(key)
+			}
+		}
+
+		_, _ = resp.Write([]byte(`{"result":"OK"}`))
+
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/xml")
+	_, _ = resp.Write([]byte(xml.Header + "<settings>\n"))
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		_, _ = fmt.Fprintf(resp, "  <%s>%s</%s>\n", key, xmlEscape(values[key]), key)
+	}
+
+	_, _ = resp.Write([]byte("</settings>\n"))
+}
+
+func (c *Camera) xml() cameraXML {
+	return cameraXML{
+		Number:               c.Number,
+		Name:                 c.Name,
+		Connected:            c.Connected,
+		Address:              c.Address,
+		DeviceName:           c.DeviceName,
+		DeviceType:           "Network",
+		Width:                c.Width,
+		Height:               c.Height,
+		VideoFormat:          c.VideoFormat,
+		HasAudio:             c.HasAudio,
+		CurrentFPS:           c.CurrentFPS,
+		DataRate:             c.DataRate,
+		LastError:            c.LastError,
+		LastErrorDescription: c.LastErrorDescription,
+		TimeSinceLastFrame:   c.TimeSinceLastFrame,
+		TimeSinceLastMotion:  lastMotion(c),
+		PTZ:                  c.PTZ,
+		ModeC:                armText(c.ModeC),
+		ModeM:                armText(c.ModeM),
+		ModeA:                armText(c.ModeA),
+		ScheduleCC:           c.ScheduleCC,
+		ScheduleMC:           c.ScheduleMC,
+		ScheduleA:            c.ScheduleA,
+		OverrideCC:           c.OverrideCC,
+		OverrideMC:           c.OverrideMC,
+		OverrideA:            c.OverrideA,
+		StoragePath:          "/Cameras/" + c.Name,
+	}
+}
+
+func armText(armed bool) string {
+	if armed {
+		return "armed"
+	}
+
+	return "disarmed"
+}
+
+// lastMotion is empty until the camera sees motion, the way SecuritySpy sends it.
+func lastMotion(c *Camera) string {
+	if c.NoMotionYet {
+		return ""
+	}
+
+	return strconv.Itoa(c.TimeSinceLastMotion)
+}
+
+func scheduleList(schedules map[int]string) []scheduleXML {
+	list := make([]scheduleXML, 0, len(schedules))
+	for _, id := range slices.Sorted(maps.Keys(schedules)) {
+		list = append(list, scheduleXML{ID: id, Name: schedules[id]})
+	}
+
+	return list
+}
+
+func writeXML(resp http.ResponseWriter, val any) {
+	out, err := xml.Marshal(val)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/xml")
+	_, _ = resp.Write([]byte(xml.Header))
+	_, _ = resp.Write(out)
+}
+
+func xmlEscape(text string) string {
+	var buf strings.Builder
+
+	_ = xml.EscapeText(&buf, []byte(text))
+
+	return buf.String()
+}
+
+func atoi(text string) int {
+	val, _ := strconv.Atoi(text)
+	return val
+}
+
+// parseContinuation decodes the hex offset this fake server puts in <continuation>.
+func parseContinuation(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	val, err := strconv.ParseInt(text, 16, 64)
+	if err != nil {
+		return 0
+	}
+
+	return int(val)
+}
+
+// queryInts returns every integer value for a repeated query parameter.
+func queryInts(query url.Values, key string) []int {
+	vals := make([]int, 0, len(query[key]))
+
+	for _, v := range query[key] {
+		if n, err := strconv.Atoi(v); err == nil {
+			vals = append(vals, n)
+		}
+	}
+
+	return vals
+}
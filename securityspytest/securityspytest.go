@@ -0,0 +1,404 @@
+// Package securityspytest provides a stateful fake SecuritySpy server for tests.
+// It holds cameras, schedules, groups, settings and saved files, changes its state
+// when arm/disarm, PTZ and schedule commands arrive, serves JPEGs and paginated
+// ++download feeds, lets tests push event-stream lines, and records every request.
+//
+//	fake := securityspytest.NewServer()
+//	defer fake.Close()
+//
+//	sspy, err := securityspy.New(fake.Config())
+package securityspytest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golift.io/securityspy/v2/server"
+)
+
+// EventTimeFormat is the time stamp format written to the fake event stream.
+const EventTimeFormat = "20060102150405"
+
+// Server is a fake SecuritySpy server. Create one with NewServer and Close it when done.
+// All methods are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	username  string
+	password  string
+	info      ServerInfo
+	cameras   map[int]*Camera
+	groups    []Group
+	schedules map[int]string
+	overrides map[int]string
+	presets   map[int]string
+	preset    int
+	settings  map[string]map[string]string
+	camConfig map[int]map[string]string
+	files     []File
+	pageSize  int
+	jpeg      []byte
+	scripts   []string
+	sounds    []string
+	handlers  map[string]http.HandlerFunc
+	requests  []Request
+	eventNum  int
+	streams   map[chan string]struct{}
+}
+
+// NewServer starts a fake SecuritySpy server seeded with a v6.20 inventory:
+// camera 2 "Porch" (offline) and camera 3 "Door" (online, PTZ capable), four
+// schedules and the standard schedule overrides. No credentials are required
+// until SetAuth is called.
+func NewServer() *Server {
+	fake := &Server{
+		info: ServerInfo{
+			Name:           "SecuritySpy",
+			Version:        "6.20",
+			UUID:           "FAKEUUID000000000001",
+			ServerName:     "Fake Server",
+			BonjourName:    "securityspy.local",
+			IP1:            "192.0.2.1",
+			HTTPPort:       8000,
+			HTTPSPort:      8001,
+			DateFormat:     "MM/DD/YYYY",
+			TimeFormat:     "24",
+			CertExpiryDays: 90,
+		},
+		cameras: map[int]*Camera{
+			2: {Number: 2, Name: "Porch", Address: "192.0.2.12", DeviceName: "Fake Camera",
+				Width: 3072, Height: 2048, ScheduleMC: 1, ScheduleA: 1},
+			3: {Number: 3, Name: "Door", Address: "192.0.2.13", DeviceName: "Fake Camera", Connected: true,
+				Width: 3072, Height: 2048, VideoFormat: "H.264", HasAudio: true, CurrentFPS: 20, DataRate: 1279873,
+				PTZ: 61, ModeM: true, ModeA: true, ScheduleMC: 1, ScheduleA: 1},
+		},
+		groups: []Group{{Number: 0, Name: "Base", Cameras: []int{2, 3}}},
+		schedules: map[int]string{
+			0: "Disarmed 24/7", 1: "Armed 24/7", 2: "Armed Sunrise To Sunset", 3: "Armed Sunset To Sunrise",
+		},
+		overrides: map[int]string{
+			0: "No Override", 1: "Disarmed Until Schedule Event", 2: "Armed Until Schedule Event",
+		},
+		presets:   map[int]string{},
+		preset:    -1,
+		settings:  map[string]map[string]string{},
+		camConfig: map[int]map[string]string{},
+		scripts:   []string{"Script.scpt"},
+		sounds:    []string{"Beeps.aif"},
+		handlers:  map[string]http.HandlerFunc{},
+		streams:   map[chan string]struct{}{},
+		jpeg:      defaultJPEG(),
+	}
+
+	fake.Server = httptest.NewServer(fake)
+
+	return fake
+}
+
+// Close disconnects event stream clients and shuts down the server.
+func (s *Server) Close() {
+	s.CloseEventStreams()
+	s.Server.Close()
+}
+
+// Config returns a library config pointed at the fake server, using the
+// credentials from SetAuth (if any). Pass it to securityspy.New or NewMust.
+func (s *Server) Config() *server.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &server.Config{
+		URL:      s.URL + "/",
+		Username: s.username,
+		Password: s.password,
+		Timeout:  server.Duration{Duration: 5 * time.Second}, //nolint:mnd // plenty for a local server.
+	}
+}
+
+// SetAuth requires every request to carry auth= credentials for this user and password.
+// Requests without them receive HTTP 401. Pass empty strings to disable authentication.
+func (s *Server) SetAuth(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.username = username
+	s.password = password
+}
+
+// SetInfo modifies the <server> block returned by ++systemInfo.
+func (s *Server) SetInfo(update func(*ServerInfo)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(&s.info)
+}
+
+// Info returns a copy of the fake server's info block.
+func (s *Server) Info() ServerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.info
+}
+
+// AddCamera adds or replaces a camera, keyed by camera number.
+func (s *Server) AddCamera(camera Camera) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cameras[camera.Number] = &camera
+}
+
+// RemoveCamera deletes a camera by number.
+func (s *Server) RemoveCamera(number int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.cameras, number)
+}
+
+// UpdateCamera modifies a camera in place. Returns false if the camera does not exist.
+func (s *Server) UpdateCamera(number int, update func(*Camera)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	camera, ok := s.cameras[number]
+	if ok {
+		update(camera)
+	}
+
+	return ok
+}
+
+// Camera returns a copy of a camera's current state.
+func (s *Server) Camera(number int) (Camera, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	camera, ok := s.cameras[number]
+	if !ok {
+		return Camera{}, false
+	}
+
+	out := *camera
+	out.PTZCommands = slices.Clone(camera.PTZCommands)
+
+	return out, true
+}
+
+// SetGroups replaces the camera group list.
+func (s *Server) SetGroups(groups ...Group) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.groups = slices.Clone(groups)
+}
+
+// AddSchedule adds or renames a schedule.
+func (s *Server) AddSchedule(id int, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedules[id] = name
+}
+
+// AddScheduleOverride adds or renames a schedule override.
+func (s *Server) AddScheduleOverride(id int, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.overrides[id] = name
+}
+
+// AddSchedulePreset adds or renames a schedule preset.
+func (s *Server) AddSchedulePreset(id int, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.presets[id] = name
+}
+
+// ActivePreset returns the last schedule preset invoked with ++ssSetPreset, or -1.
+func (s *Server) ActivePreset() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.preset
+}
+
+// SetSettings merges values into a ++settings-<section> page, ie. "general" or "web".
+func (s *Server) SetSettings(section string, values map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.settings[section] == nil {
+		s.settings[section] = map[string]string{}
+	}
+
+	maps.Copy(s.settings[section], values)
+}
+
+// Settings returns a copy of a ++settings-<section> page.
+func (s *Server) Settings(section string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.settings[section])
+}
+
+// SetCameraSettings merges values into the ++settings-cameras page for one camera.
+func (s *Server) SetCameraSettings(cameraNum int, values map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.camConfig[cameraNum] == nil {
+		s.camConfig[cameraNum] = map[string]string{}
+	}
+
+	maps.Copy(s.camConfig[cameraNum], values)
+}
+
+// CameraSettings returns a copy of the ++settings-cameras page for one camera.
+func (s *Server) CameraSettings(cameraNum int) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.camConfig[cameraNum])
+}
+
+// AddFile adds saved media files to the ++download feed.
+func (s *Server) AddFile(files ...File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files = append(s.files, files...)
+	sort.SliceStable(s.files, func(i, j int) bool { return s.files[i].Time.Before(s.files[j].Time) })
+}
+
+// SetPageSize limits how many entries each ++download page returns, which makes the
+// library follow continuations. 0 (the default) honors the request's results= value.
+func (s *Server) SetPageSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pageSize = size
+}
+
+// SetJPEG replaces the bytes served by ++image.
+func (s *Server) SetJPEG(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jpeg = slices.Clone(data)
+}
+
+// SetScripts replaces the ++scripts list.
+func (s *Server) SetScripts(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scripts = slices.Clone(names)
+}
+
+// SetSounds replaces the ++sounds list.
+func (s *Server) SetSounds(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sounds = slices.Clone(names)
+}
+
+// Requests returns every request received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+// LastRequest returns the most recent request for a path, ie. "/++ptz/command".
+func (s *Server) LastRequest(path string) (Request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, req := range slices.Backward(s.requests) {
+		if req.Path == path {
+			return req, true
+		}
+	}
+
+	return Request{}, false
+}
+
+// CountRequests returns how many requests were received for a path.
+func (s *Server) CountRequests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+
+	for _, req := range s.requests {
+		if req.Path == path {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ResetRequests forgets all recorded requests.
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+}
+
+// authBlob returns the expected auth= value. Call with the lock held.
+func (s *Server) authBlob() string {
+	if s.username == "" && s.password == "" {
+		return ""
+	}
+
+	return base64.URLEncoding.EncodeToString([]byte(s.username + ":" + s.password))
+}
+
+// sortedCameras returns camera numbers in order. Call with the lock held.
+func (s *Server) sortedCameras() []int {
+	return slices.Sorted(maps.Keys(s.cameras))
+}
+
+func defaultJPEG() []byte {
+	const size = 8
+
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for idx := range img.Pix {
+		img.Pix[idx] = color.Gray{Y: 128}.Y //nolint:mnd // mid gray.
+	}
+
+	var buf bytes.Buffer
+
+	_ = jpeg.Encode(&buf, img, nil)
+
+	return buf.Bytes()
+}
+
+func cameraString(number int) string {
+	if number < 0 {
+		return "X"
+	}
+
+	return strconv.Itoa(number)
+}
@@ -0,0 +1,206 @@
+package securityspytest_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+func newClient(t *testing.T) (*securityspytest.Server, *securityspy.Server) {
+	t.Helper()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	return fake, sspy
+}
+
+func TestRefreshInventory(t *testing.T) {
+	t.Parallel()
+
+	fake, sspy := newClient(t)
+
+	require.Equal(t, "6.20", sspy.Snapshot().Info.Version)
+	require.Len(t, sspy.Snapshot().Cameras.All(), 2)
+	require.Equal(t, "Armed 24/7", sspy.Snapshot().Cameras.ByNum(3).ScheduleIDMC.Name)
+	require.Equal(t, []int{2, 3}, sspy.Snapshot().Groups[0].CameraNumbers())
+
+	fake.AddCamera(securityspytest.Camera{Number: 7, Name: "Gate", Connected: true})
+	require.NoError(t, sspy.Refresh())
+	require.Equal(t, "Gate", sspy.Snapshot().Cameras.ByNum(7).Name)
+	require.Equal(t, 2, fake.CountRequests("/++systemInfo"))
+}
+
+func TestArmCommandsChangeState(t *testing.T) {
+	t.Parallel()
+
+	fake, sspy := newClient(t)
+	door := sspy.Snapshot().Cameras.ByNum(3)
+
+	require.NoError(t, door.ToggleContinuous(securityspy.CameraArm))
+	require.NoError(t, door.ToggleMotion(securityspy.CameraDisarm))
+
+	state, ok := fake.Camera(3)
+	require.True(t, ok)
+	require.True(t, state.ModeC)
+	require.False(t, state.ModeM)
+
+	modes, err := door.Modes()
+	require.NoError(t, err)
+	require.Equal(t, "ARMED", modes.Continuous)
+	require.Equal(t, "DISARMED", modes.Motion)
+
+	require.NoError(t, sspy.Refresh())
+	require.True(t, sspy.Snapshot().Cameras.ByNum(3).ModeC.Val)
+}
+
+func TestScheduleAndPTZCommands(t *testing.T) {
+	t.Parallel()
+
+	fake, sspy := newClient(t)
+	door := sspy.Snapshot().Cameras.ByNum(3)
+
+	require.NoError(t, door.SetSchedule(securityspy.CameraModeAll, 2))
+	require.NoError(t, door.SetScheduleOverride(securityspy.CameraModeMotion, 1))
+	require.Error(t, door.SetSchedule(securityspy.CameraModeMotion, 99))
+	require.NoError(t, door.PTZ.Home())
+	require.NoError(t, door.PTZ.Preset(securityspy.PTZpreset2))
+	require.Error(t, sspy.Snapshot().Cameras.ByNum(2).PTZ.Home(), "camera 2 has no PTZ features")
+	require.NoError(t, door.TriggerMotion())
+
+	state, _ := fake.Camera(3)
+	require.Equal(t, 2, state.ScheduleCC)
+	require.Equal(t, 2, state.ScheduleMC)
+	require.Equal(t, 1, state.OverrideMC)
+	require.Equal(t, 0, state.OverrideA)
+	require.Equal(t, []int{7, 13}, state.PTZCommands)
+	require.Equal(t, 1, state.Triggers)
+
+	fake.AddSchedulePreset(5, "Away")
+	require.Equal(t, -1, fake.ActivePreset())
+	require.NoError(t, sspy.SetSchedulePreset(5))
+	require.Equal(t, 5, fake.ActivePreset())
+}
+
+func TestJPEGAndFiles(t *testing.T) {
+	t.Parallel()
+
+	fake, sspy := newClient(t)
+
+	img, err := sspy.Snapshot().Cameras.ByNum(3).GetJPEG(nil)
+	require.NoError(t, err)
+	require.Equal(t, 8, img.Bounds().Dx())
+
+	_, err = sspy.Snapshot().Cameras.ByNum(2).GetJPEG(nil)
+	require.ErrorIs(t, err, securityspy.ErrCameraUnavailable, "offline cameras return 404")
+
+	start := time.Date(2026, 7, 19, 10, 0, 0, 0, time.Local)
+	for idx := range 5 {
+		fake.AddFile(securityspytest.File{CameraNum: 3, Capture: securityspytest.CaptureMotion,
+			Time: start.Add(time.Duration(idx) * time.Minute), Data: []byte("movie")})
+	}
+
+	fake.AddFile(securityspytest.File{CameraNum: 2, Capture: securityspytest.CaptureContinuous, Time: start})
+	fake.SetPageSize(2)
+
+	files, err := sspy.Files.GetMCVideos([]int{3}, start, start)
+	require.NoError(t, err)
+	require.Len(t, files, 5)
+	require.Equal(t, 3, fake.CountRequests("/++download"), "5 files at 2 per page is 3 pages")
+	require.Equal(t, "07-19-2026 10-00-00 M Door.m4v", files[0].Title)
+
+	body, err := files[0].Get(true)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+
+	req, ok := fake.LastRequest("/++getfilehb/3/2026-07-19/07-19-2026+10-00-00+M+Door.m4v")
+	require.True(t, ok)
+	require.Equal(t, "GET", req.Method)
+}
+
+func TestSettingsRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	fake, sspy := newClient(t)
+	fake.SetSettings("general", map[string]string{"sysName": "Fake", "autoReopen": "true"})
+
+	general, err := sspy.GetGeneralSettings()
+	require.NoError(t, err)
+	require.Equal(t, "Fake", general.SysName)
+	require.True(t, general.AutoReopen.Val)
+
+	require.NoError(t, sspy.SetGeneralSettings(url.Values{"sysName": {"Renamed"}}))
+	require.Equal(t, "Renamed", fake.Settings("general")["sysName"])
+
+	require.NoError(t, sspy.SetCameraSettings(url.Values{"cameraNum": {"3"}, "aReset": {"20"}}))
+	require.Equal(t, "20", fake.CameraSettings(3)["aReset"])
+
+	fake.SetInfo(func(info *securityspytest.ServerInfo) { info.Version = "5.5.4" })
+
+	_, err = sspy.GetGeneralSettings()
+	require.Error(t, err, "settings pages do not exist on v5")
+}
+
+func TestEventStream(t *testing.T) {
+	t.Parallel()
+
+	fake, sspy := newClient(t)
+	events := make(chan securityspy.Event, 10)
+
+	sspy.Events.BindChan(securityspy.EventTriggerMotion, events)
+	sspy.Events.BindChan(securityspy.EventArmContinuous, events)
+	sspy.Events.BindChan(securityspy.EventStreamConnect, events)
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	require.Equal(t, securityspy.EventStreamConnect, (<-events).Type)
+	require.Eventually(t, func() bool { return fake.EventStreamClients() == 1 }, time.Second, time.Millisecond)
+
+	fake.PushEvent(3, "TRIGGER_M", "9")
+
+	event := <-events
+	require.Equal(t, securityspy.EventTriggerMotion, event.Type)
+	require.Equal(t, 3, event.Camera.Number)
+	require.Len(t, event.Reasons, 2)
+
+	require.NoError(t, sspy.Snapshot().Cameras.ByNum(3).ToggleContinuous(securityspy.CameraArm))
+	require.Equal(t, securityspy.EventArmContinuous, (<-events).Type)
+}
+
+func TestAuthAndRequestLog(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	defer fake.Close()
+
+	fake.SetAuth("admin", "secret")
+
+	config := fake.Config()
+	sspy, err := securityspy.New(config)
+	require.NoError(t, err)
+
+	req, ok := fake.LastRequest("/++systemInfo")
+	require.True(t, ok)
+	require.Equal(t, "xml", req.Query.Get("format"))
+	require.NotEmpty(t, req.Query.Get("auth"))
+
+	config = fake.Config()
+	config.Password = "wrong"
+
+	_, err = securityspy.New(config)
+	require.Error(t, err)
+
+	fake.ResetRequests()
+	require.Empty(t, fake.Requests())
+
+	_, err = sspy.GetScripts()
+	require.NoError(t, err)
+	require.Len(t, fake.Requests(), 1)
+}
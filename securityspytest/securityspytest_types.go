@@ -0,0 +1,189 @@
+package securityspytest
+
+import (
+	"encoding/xml"
+	"net/url"
+	"time"
+)
+
+// Capture kinds for fake files. These match the letter SecuritySpy puts in saved file names.
+const (
+	CaptureMotion     = 'M'
+	CaptureContinuous = 'C'
+	CaptureImage      = 'I'
+)
+
+// ServerInfo is the fake server's ++systemInfo <server> block.
+// Modify it with Server.SetInfo.
+type ServerInfo struct {
+	Name           string
+	Version        string
+	UUID           string
+	ServerName     string
+	BonjourName    string
+	IP1            string
+	HTTPPort       int
+	HTTPSPort      int
+	GmtOffset      int // seconds from GMT
+	DateFormat     string
+	TimeFormat     string
+	CPUUsage       int
+	MemoryPressure int
+	CertExpiryDays int
+	NewVersion     string
+}
+
+// Camera is a fake camera. Arm, schedule, PTZ and trigger commands change these fields.
+type Camera struct {
+	Number               int
+	Name                 string
+	Connected            bool
+	Address              string
+	DeviceName           string
+	Width                int
+	Height               int
+	VideoFormat          string
+	HasAudio             bool
+	CurrentFPS           float64
+	DataRate             int
+	LastError            int
+	LastErrorDescription string
+	TimeSinceLastFrame   int  // seconds
+	TimeSinceLastMotion  int  // seconds
+	NoMotionYet          bool // Sends an empty time-since-last-motion, like a camera that has not seen motion.
+	PTZ                  int  // ptz-features bitmask; 0 rejects PTZ commands.
+	ModeC                bool
+	ModeM                bool
+	ModeA                bool
+	ScheduleCC           int
+	ScheduleMC           int
+	ScheduleA            int
+	OverrideCC           int
+	OverrideMC           int
+	OverrideA            int
+	// The following are written by the fake server when commands arrive.
+	PTZCommands []int // every ++ptz/command received, in order.
+	Triggers    int   // count of ++triggermd requests.
+}
+
+// Group is a fake camera group.
+type Group struct {
+	Number  int
+	Name    string
+	Cameras []int
+}
+
+// File is a fake saved media file served by ++download and ++getfile.
+type File struct {
+	CameraNum int
+	Capture   rune      // CaptureMotion, CaptureContinuous or CaptureImage.
+	Time      time.Time // Capture time; used for the name, folder and date filters.
+	Data      []byte    // Served by ++getfile. A placeholder is used when empty.
+}
+
+// Request is a recorded request to the fake server.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Form   url.Values // POST form values, if any.
+	Time   time.Time
+}
+
+// systemInfoXML is rendered for ++systemInfo using v6 tag names.
+type systemInfoXML struct {
+	XMLName   xml.Name      `xml:"system"`
+	Cameras   []cameraXML   `xml:"camera-list>camera"`
+	Groups    []groupXML    `xml:"group-list>group"`
+	Schedules []scheduleXML `xml:"schedule-list>schedule"`
+	Overrides []scheduleXML `xml:"schedule-override-list>schedule-override"`
+	Presets   []scheduleXML `xml:"schedule-preset-list>schedule-preset"`
+	Server    serverXML     `xml:"server"`
+}
+
+type serverXML struct {
+	Name           string `xml:"name"`
+	Version        string `xml:"version"`
+	UUID           string `xml:"uuid"`
+	ServerName     string `xml:"server-name"`
+	BonjourName    string `xml:"bonjour-name"`
+	IP1            string `xml:"ip1"`
+	HTTPPort       int    `xml:"http-port"`
+	HTTPSPort      int    `xml:"https-port"`
+	CurrentTime    string `xml:"current-local-time"`
+	GmtOffset      int    `xml:"seconds-from-gmt"`
+	DateFormat     string `xml:"date-format"`
+	TimeFormat     string `xml:"time-format"`
+	CPUUsage       int    `xml:"cpu-usage"`
+	MemoryPressure int    `xml:"memory-pressure"`
+	CertExpiryDays int    `xml:"cert-expiry-days"`
+	CameraCount    int    `xml:"camera-count"`
+	NewVersion     string `xml:"new-version"`
+}
+
+type cameraXML struct {
+	Number               int     `xml:"number"`
+	Name                 string  `xml:"name"`
+	Connected            bool    `xml:"connected"`
+	Address              string  `xml:"address"`
+	DeviceName           string  `xml:"device-name"`
+	DeviceType           string  `xml:"device-type"`
+	Width                int     `xml:"video-width"`
+	Height               int     `xml:"video-height"`
+	VideoFormat          string  `xml:"video-format"`
+	HasAudio             bool    `xml:"has-audio"`
+	CurrentFPS           float64 `xml:"current-fps"`
+	DataRate             int     `xml:"data-rate"`
+	LastError            int     `xml:"last-error"`
+	LastErrorDescription string  `xml:"last-error-description"`
+	TimeSinceLastFrame   int     `xml:"time-since-last-frame"`
+	TimeSinceLastMotion  string  `xml:"time-since-last-motion"`
+	PTZ                  int     `xml:"ptz-features"`
+	ModeC                string  `xml:"cc-mode"`
+	ModeM                string  `xml:"mc-mode"`
+	ModeA                string  `xml:"a-mode"`
+	ScheduleCC           int     `xml:"cc-schedule-id"`
+	ScheduleMC           int     `xml:"mc-schedule-id"`
+	ScheduleA            int     `xml:"a-schedule-id"`
+	OverrideCC           int     `xml:"cc-schedule-override"`
+	OverrideMC           int     `xml:"mc-schedule-override"`
+	OverrideA            int     `xml:"a-schedule-override"`
+	StoragePath          string  `xml:"storage-path"`
+}
+
+type groupXML struct {
+	Number  int    `xml:"number"`
+	Name    string `xml:"name"`
+	Cameras string `xml:"cameras"`
+}
+
+type scheduleXML struct {
+	ID   int    `xml:"id"`
+	Name string `xml:"name"`
+}
+
+// fileFeedXML is rendered for ++download.
+type fileFeedXML struct {
+	XMLName      xml.Name       `xml:"feed"`
+	Title        string         `xml:"title"`
+	GmtOffset    int            `xml:"gmt-offset"`
+	Continuation string         `xml:"continuation,omitempty"`
+	Entries      []fileEntryXML `xml:"entry"`
+}
+
+type fileEntryXML struct {
+	Title string `xml:"title"`
+	Link  struct {
+		Rel    string `xml:"rel,attr"`
+		Type   string `xml:"type,attr"`
+		Length int    `xml:"length,attr"`
+		HREF   string `xml:"href,attr"`
+	} `xml:"link"`
+	Updated   string `xml:"updated"`
+	CameraNum int    `xml:"cameraNum"`
+}
+
+type namesXML struct {
+	XMLName xml.Name `xml:"names"`
+	Names   []string `xml:"name"`
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MaxErrorBody is the most response body bytes kept in an APIError.
+const MaxErrorBody = 512
+
+// These sentinels classify an APIError. Use errors.Is to branch on them.
+// ErrNotFound and ErrCmdNotOK also classify APIErrors.
+var (
+	// ErrAuthFailed is returned when SecuritySpy responds with HTTP 401 or 403.
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrUnsupported is returned when SecuritySpy does not implement an endpoint or method.
+	ErrUnsupported = errors.New("securityspy endpoint unsupported")
+	// ErrServerBusy is returned when SecuritySpy responds with HTTP 429, 502, 503 or 504.
+	ErrServerBusy = errors.New("server busy")
+	// ErrParseFailed is returned when a response body cannot be decoded.
+	ErrParseFailed = errors.New("response parse failed")
+	// ErrTransport is returned when a request gets no HTTP response at all.
+	ErrTransport = errors.New("transport failure")
+	// ErrBadStatus is returned for any other non-200 HTTP status.
+	ErrBadStatus = errors.New("unexpected http status")
+)
+
+// ErrorKind classifies an APIError.
+type ErrorKind string
+
+// These are the possible APIError classifications.
+const (
+	KindTransport   ErrorKind = "transport"   // No response: dial, TLS, timeout or reset.
+	KindAuth        ErrorKind = "auth"        // HTTP 401 or 403.
+	KindNotFound    ErrorKind = "not-found"   // HTTP 404.
+	KindUnsupported ErrorKind = "unsupported" // HTTP 405 or 501.
+	KindBusy        ErrorKind = "busy"        // HTTP 429, 502, 503 or 504.
+	KindParse       ErrorKind = "parse"       // The body could not be decoded.
+	KindCommand     ErrorKind = "command"     // The reply did not end with OK.
+	KindStatus      ErrorKind = "status"      // Any other non-200 status.
+)
+
+// APIError is returned by the request helpers in this package when a request fails.
+// It never contains credentials: the URL has auth= redacted and the body excerpt is scrubbed.
+// errors.Is matches the sentinel for its Kind (ErrNotFound, ErrAuthFailed, ...) and
+// ErrCmdNotOK for failed commands, so callers can branch without parsing strings.
+type APIError struct {
+	Kind     ErrorKind
+	Method   string // GET or POST.
+	Endpoint string // API path, ie. ++systemInfo.
+	URL      string // Full request URL with auth redacted.
+	Status   int    // HTTP status code; 0 when no response was received.
+	Body     string // Up to MaxErrorBody bytes of the response body.
+	Err      error  // Underlying cause, may be nil.
+}
+
+// Error formats the failure, including the redacted endpoint and status.
+func (e *APIError) Error() string {
+	var msg string
+
+	switch e.Kind {
+	case KindTransport:
+		msg = fmt.Sprintf("http request: %s %s: %v", e.Method, e.Endpoint, e.Err)
+	case KindParse:
+		msg = fmt.Sprintf("reading body: %s %s: %v", e.Method, e.Endpoint, e.Err)
+	case KindCommand:
+		msg = fmt.Sprintf("%v: %s %s (status: %d)", ErrCmdNotOK, e.Method, e.Endpoint, e.Status)
+	default:
+		msg = fmt.Sprintf("request failed: %s %s (status: %d %s): %v",
+			e.Method, e.Endpoint, e.Status, http.StatusText(e.Status), e.sentinel())
+	}
+
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+
+	return msg
+}
+
+// Unwrap returns the sentinel for this error's Kind and the underlying cause.
+func (e *APIError) Unwrap() []error {
+	errs := []error{e.sentinel()}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+
+	return errs
+}
+
+func (e *APIError) sentinel() error {
+	switch e.Kind {
+	case KindTransport:
+		return ErrTransport
+	case KindAuth:
+		return ErrAuthFailed
+	case KindNotFound:
+		return ErrNotFound
+	case KindUnsupported:
+		return ErrUnsupported
+	case KindBusy:
+		return ErrServerBusy
+	case KindParse:
+		return ErrParseFailed
+	case KindCommand:
+		return ErrCmdNotOK
+	default:
+		return ErrBadStatus
+	}
+}
+
+// KindOf returns the classification of an error produced by this package.
+// Returns an empty ErrorKind if err is not (and does not wrap) an APIError.
+func KindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+
+	return ""
+}
+
+// StatusKind classifies an HTTP status code. 200 returns an empty ErrorKind.
+func StatusKind(status int) ErrorKind {
+	switch status {
+	case http.StatusOK:
+		return ""
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return KindAuth
+	case http.StatusNotFound:
+		return KindNotFound
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return KindUnsupported
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return KindBusy
+	default:
+		return KindStatus
+	}
+}
+
+// newAPIError builds an APIError for a request, redacting credentials from the URL and body.
+func (s *Config) newAPIError(
+	req *http.Request, apiPath string, kind ErrorKind, status int, body []byte, err error,
+) *APIError {
+	if uerr, ok := err.(*url.Error); ok { //nolint:errorlint // http.Client returns this type unwrapped.
+		// url.Error prints the request URL, including the auth parameter.
+		redactedErr := *uerr
+		if parsed, perr := url.Parse(uerr.URL); perr == nil {
+			redactedErr.URL = RedactURL(parsed)
+		}
+
+		err = &redactedErr
+	}
+
+	apiErr := &APIError{Kind: kind, Endpoint: apiPath, Status: status, Err: err}
+	if req != nil { // Custom transports may not set resp.Request.
+		apiErr.Method = req.Method
+		apiErr.URL = RedactURL(req.URL)
+	}
+
+	if len(body) > MaxErrorBody {
+		body = body[:MaxErrorBody]
+	}
+
+	apiErr.Body = strings.TrimSpace(string(body))
+	if s.Password != "" {
+		apiErr.Body = strings.ReplaceAll(apiErr.Body, s.Password, redacted)
+	}
+
+	return apiErr
+}
+
+// ResponseError reads up to MaxErrorBody bytes of a failed response and closes its body.
+// Returns an APIError classified by the status, with credentials redacted from the URL and body.
+func (s *Config) ResponseError(resp *http.Response, apiPath string) *APIError {
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, MaxErrorBody))
+
+	return s.newAPIError(resp.Request, apiPath, StatusKind(resp.StatusCode), resp.StatusCode, body, nil)
+}
+
+const redacted = "REDACTED"
+
+// RedactURL returns u as a string with the auth= parameter and any userinfo password replaced.
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	out := *u
+
+	if out.User != nil {
+		if _, ok := out.User.Password(); ok {
+			out.User = url.UserPassword(out.User.Username(), redacted)
+		}
+	}
+
+	if query := out.Query(); query.Has("auth") {
+		query.Set("auth", redacted)
+		out.RawQuery = query.Encode()
+	}
+
+	return out.String()
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestInfo describes one HTTP request (one attempt) made to SecuritySpy.
+// Observers receive the same pointer in RequestStart and RequestEnd.
+type RequestInfo struct {
+	Operation string        // Logical operation, ie. "ptz.command" or "files.page".
+	Method    string        // GET or POST.
+	Endpoint  string        // API path, ie. ++ptz/command.
+	URL       string        // Request URL with auth redacted.
+	Camera    int           // Camera number, or -1 if the request is not for one camera.
+	Attempt   int           // 1 for the first try, higher for retries.
+	Start     time.Time     // When the request was sent.
+	Duration  time.Duration // Set before RequestEnd.
+	Status    int           // HTTP status, set before RequestEnd. 0 if there was no response.
+	Err       error         // Set before RequestEnd. Usually an *APIError.
+}
+
+// Observer is notified about every request the library makes. Use it to log, time or trace requests.
+// Set one on Config.Observer; combine several with Observers.
+type Observer interface {
+	// RequestStart is called before a request is sent. The returned context is used for
+	// the request and passed to RequestEnd, so a tracer can carry a span in it.
+	RequestStart(ctx context.Context, info *RequestInfo) context.Context
+	// RequestEnd is called when the request finishes. For streams and raw GETs that is when
+	// the response headers arrive; for other helpers it is after the body is processed.
+	RequestEnd(ctx context.Context, info *RequestInfo)
+}
+
+// operations maps API paths to logical operation names.
+var operations = map[string]string{ //nolint:gochecknoglobals
+	"++systemInfo":             "server.info",
+	"++scripts":                "server.scripts",
+	"++sounds":                 "server.sounds",
+	"++eventStream":            "events.stream",
+	"++download":               "files.page",
+	"++image":                  "camera.image",
+	"++video":                  "camera.video",
+	"++stream":                 "camera.stream",
+	"++audio":                  "camera.audio",
+	"++cameramodes":            "camera.modes",
+	"++ssControlContinuous":    "camera.arm.continuous",
+	"++ssControlMotionCapture": "camera.arm.motion",
+	"++ssControlActions":       "camera.arm.actions",
+	"++triggermd":              "camera.trigger",
+	"++ssSetSchedule":          "camera.schedule",
+	"++setSchedule":            "camera.schedule",
+	"++ssSetOverride":          "camera.override",
+	"++ssSetPreset":            "schedules.preset",
+	"++ptz/command":            "ptz.command",
+}
+
+// Operation returns the logical operation name for a request, ie. "ptz.command" or "settings.general.set".
+// Unknown endpoints return the API path without its ++ prefix.
+func Operation(method, apiPath string) string {
+	if op, ok := operations[apiPath]; ok {
+		if apiPath == "++audio" && method == http.MethodPost {
+			return "camera.audio.send"
+		}
+
+		return op
+	}
+
+	switch {
+	case strings.HasPrefix(apiPath, "++getfile"):
+		return "files.get"
+	case strings.HasPrefix(apiPath, "++settings-"):
+		if method == http.MethodPost {
+			return "settings." + strings.TrimPrefix(apiPath, "++settings-") + ".set"
+		}
+
+		return "settings." + strings.TrimPrefix(apiPath, "++settings-") + ".get"
+	default:
+		return strings.TrimPrefix(apiPath, "++")
+	}
+}
+
+type attemptKey struct{}
+
+// startRequest notifies the observer and returns the request with the observer's context.
+// Returns a nil RequestInfo if no observer is configured.
+func (s *Config) startRequest(req *http.Request, apiPath string, params url.Values) (*http.Request, *RequestInfo) {
+	if s.Observer == nil {
+		return req, nil
+	}
+
+	info := &RequestInfo{
+		Operation: Operation(req.Method, apiPath),
+		Method:    req.Method,
+		Endpoint:  apiPath,
+		URL:       RedactURL(req.URL),
+		Camera:    -1,
+		Attempt:   1,
+		Start:     time.Now(),
+	}
+
+	if num, err := strconv.Atoi(params.Get("cameraNum")); err == nil {
+		info.Camera = num
+	}
+
+	if attempt, ok := req.Context().Value(attemptKey{}).(int); ok {
+		info.Attempt = attempt
+	}
+
+	ctx := s.Observer.RequestStart(req.Context(), info)
+
+	return req.WithContext(ctx), info
+}
+
+// endRequest fills in the result and notifies the observer. Does nothing if info is nil.
+func (s *Config) endRequest(ctx context.Context, info *RequestInfo, resp *http.Response, err error) {
+	if info == nil {
+		return
+	}
+
+	info.Duration = time.Since(info.Start)
+	info.Err = err
+
+	if resp != nil {
+		info.Status = resp.StatusCode
+	} else if apiErr, ok := err.(*APIError); ok { //nolint:errorlint // only our own unwrapped errors.
+		info.Status = apiErr.Status
+	}
+
+	s.Observer.RequestEnd(ctx, info)
+}
+
+// Observers combines several observers into one. They are started in order and ended in reverse.
+func Observers(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
+
+type multiObserver []Observer
+
+func (m multiObserver) RequestStart(ctx context.Context, info *RequestInfo) context.Context {
+	for _, obs := range m {
+		ctx = obs.RequestStart(ctx, info)
+	}
+
+	return ctx
+}
+
+func (m multiObserver) RequestEnd(ctx context.Context, info *RequestInfo) {
+	for i := len(m) - 1; i >= 0; i-- {
+		m[i].RequestEnd(ctx, info)
+	}
+}
+
+// SlogObserver logs every finished request. Successful requests are logged at Level,
+// failed requests at slog.LevelWarn. Use NewSlogObserver to create one.
+type SlogObserver struct {
+	Logger *slog.Logger
+	Level  slog.Level
+}
+
+// NewSlogObserver returns an observer that logs requests to logger at debug level.
+// A nil logger uses slog.Default().
+func NewSlogObserver(logger *slog.Logger) *SlogObserver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &SlogObserver{Logger: logger, Level: slog.LevelDebug}
+}
+
+// RequestStart satisfies the Observer interface. It does nothing.
+func (o *SlogObserver) RequestStart(ctx context.Context, _ *RequestInfo) context.Context {
+	return ctx
+}
+
+// RequestEnd logs the request.
+func (o *SlogObserver) RequestEnd(ctx context.Context, info *RequestInfo) {
+	level, msg := o.Level, "securityspy request"
+	if info.Err != nil {
+		level, msg = slog.LevelWarn, "securityspy request failed"
+	}
+
+	attrs := []slog.Attr{
+		slog.String("operation", info.Operation),
+		slog.String("method", info.Method),
+		slog.String("url", info.URL),
+		slog.Int("status", info.Status),
+		slog.Duration("duration", info.Duration),
+		slog.Int("attempt", info.Attempt),
+	}
+
+	if info.Camera >= 0 {
+		attrs = append(attrs, slog.Int("camera", info.Camera))
+	}
+
+	if info.Err != nil {
+		attrs = append(attrs, slog.String("error", info.Err.Error()))
+	}
+
+	o.Logger.LogAttrs(ctx, level, msg, attrs...)
+}
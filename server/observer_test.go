@@ -0,0 +1,154 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2/server"
+)
+
+type recordingObserver struct {
+	mu    sync.Mutex
+	ended []server.RequestInfo
+}
+
+func (r *recordingObserver) RequestStart(ctx context.Context, _ *server.RequestInfo) context.Context {
+	return ctx
+}
+
+func (r *recordingObserver) RequestEnd(_ context.Context, info *server.RequestInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ended = append(r.ended, *info)
+}
+
+type fakeSpan struct {
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+func (f *fakeSpan) SetAttribute(key string, value any) { f.attrs[key] = value }
+func (f *fakeSpan) RecordError(err error)              { f.err = err }
+func (f *fakeSpan) End()                               { f.ended = true }
+
+type fakeTracer struct {
+	names []string
+	spans []*fakeSpan
+}
+
+func (f *fakeTracer) Start(ctx context.Context, name string) (context.Context, server.Span) {
+	span := &fakeSpan{attrs: map[string]any{}}
+	f.names = append(f.names, name)
+	f.spans = append(f.spans, span)
+
+	return ctx, span
+}
+
+func TestObserverSeesEveryRequest(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/++ptz/command":
+			_, _ = resp.Write([]byte("Error: no PTZ"))
+		case "/++download":
+			_, _ = resp.Write([]byte("<feed></feed>"))
+		default:
+			_, _ = resp.Write([]byte(`{"result":"OK"}`))
+		}
+	})
+
+	httpClient, fakeServer := testingHTTPClient(handler)
+	defer fakeServer.Close()
+
+	var (
+		observer = &recordingObserver{}
+		tracer   = &fakeTracer{}
+		logs     bytes.Buffer
+		val      struct{}
+	)
+
+	config := &server.Config{
+		URL:      urlStr,
+		Password: "c2VjcmV0OnBhc3Mx",
+		Client:   httpClient,
+		Timeout:  server.Duration{time.Second},
+		Observer: server.Observers(observer, server.NewTracingObserver(tracer),
+			server.NewSlogObserver(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))),
+	}
+
+	require.ErrorIs(t, config.SimpleReq("++ptz/command", url.Values{"command": {"1"}}, 3), server.ErrCmdNotOK)
+	require.NoError(t, config.GetXML("++download", nil, &val))
+	require.NoError(t, config.PostForm("++settings-cameras", url.Values{"cameraNum": {"5"}}))
+
+	require.Len(t, observer.ended, 3)
+
+	ptz := observer.ended[0]
+	require.Equal(t, "ptz.command", ptz.Operation)
+	require.Equal(t, 3, ptz.Camera)
+	require.Equal(t, http.StatusOK, ptz.Status)
+	require.Equal(t, 1, ptz.Attempt)
+	require.ErrorIs(t, ptz.Err, server.ErrCmdNotOK, "command failures are reported, not only transport errors")
+	require.Contains(t, ptz.URL, "auth=REDACTED")
+	require.Positive(t, ptz.Duration)
+
+	require.Equal(t, "files.page", observer.ended[1].Operation)
+	require.Equal(t, -1, observer.ended[1].Camera)
+	require.Equal(t, "settings.cameras.set", observer.ended[2].Operation)
+	require.Equal(t, 5, observer.ended[2].Camera)
+
+	require.Equal(t, []string{"securityspy.ptz.command", "securityspy.files.page", "securityspy.settings.cameras.set"},
+		tracer.names)
+	require.True(t, tracer.spans[0].ended)
+	require.Equal(t, "command", tracer.spans[0].attrs["error.type"])
+	require.Equal(t, 3, tracer.spans[0].attrs["securityspy.camera"])
+	require.NoError(t, tracer.spans[1].err)
+
+	require.Contains(t, logs.String(), "operation=ptz.command")
+	require.Contains(t, logs.String(), "level=WARN")
+	require.NotContains(t, logs.String(), config.Password)
+}
+
+func TestObserverRetryAttempts(t *testing.T) {
+	t.Parallel()
+
+	handler, _ := flakyHandler(t, 1, func(resp http.ResponseWriter, _ *http.Request) {
+		_, _ = resp.Write([]byte("OK"))
+	})
+
+	httpClient, fakeServer := testingHTTPClient(handler)
+	defer fakeServer.Close()
+
+	observer := &recordingObserver{}
+	config := retryConfig(server.RetryPolicy{})
+	config.Client = httpClient
+	config.Observer = observer
+
+	resp, err := config.Get("++cameramodes", url.Values{"cameraNum": {"1"}})
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	require.Len(t, observer.ended, 2)
+	require.ErrorIs(t, observer.ended[0].Err, server.ErrTransport)
+	require.Equal(t, 2, observer.ended[1].Attempt)
+	require.Equal(t, "camera.modes", observer.ended[1].Operation)
+}
+
+func TestOperation(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "files.get", server.Operation(http.MethodGet, "++getfilehb/3/2026-01-02/file.m4v"))
+	require.Equal(t, "settings.general.get", server.Operation(http.MethodGet, "++settings-general"))
+	require.Equal(t, "camera.audio.send", server.Operation(http.MethodPost, "++audio"))
+	require.Equal(t, "camera.audio", server.Operation(http.MethodGet, "++audio"))
+	require.Equal(t, "somethingNew", server.Operation(http.MethodGet, "++somethingNew"))
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// DefaultRetryAttempts is the default number of tries for a retryable request.
+	DefaultRetryAttempts = 3
+	// DefaultRetryDelay is the default delay before the first retry. It doubles for each retry after that.
+	DefaultRetryDelay = 250 * time.Millisecond
+	// DefaultRetryMaxDelay is the default cap on the delay between retries.
+	DefaultRetryMaxDelay = 5 * time.Second
+	// DefaultRetryJitter is the default fraction of each delay that is randomized.
+	DefaultRetryJitter = 0.2
+)
+
+// DefaultRetryOn is the set of error classes retried when RetryPolicy.RetryOn is empty.
+// These are failures where the server most likely never acted on the request.
+var DefaultRetryOn = []ErrorKind{KindTransport, KindBusy} //nolint:gochecknoglobals
+
+// nonIdempotent lists GET endpoints that change state every time they are called.
+// Arm, schedule and preset setters are not listed; repeating those is harmless.
+var nonIdempotent = map[string]bool{ //nolint:gochecknoglobals
+	"++triggermd":   true,
+	"++ptz/command": true,
+}
+
+// RetryPolicy controls how failed SecuritySpy requests are retried. The zero value
+// retries idempotent GETs using the defaults above. Set MaxAttempts to 1 to disable retries.
+// Mutating calls (++triggermd, ++ptz/command and form POSTs) only retry if RetryMutating is true.
+// Post (used for audio) never retries because its body cannot be replayed.
+// Streams and GetJPEG do not use this policy; they have their own retry and reconnect logic.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first. 0 uses DefaultRetryAttempts.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. 0 uses DefaultRetryDelay.
+	BaseDelay Duration
+	// MaxDelay caps the exponential backoff. 0 uses DefaultRetryMaxDelay.
+	MaxDelay Duration
+	// Jitter is the fraction (0-1) of each delay that is randomized. 0 uses DefaultRetryJitter, negative disables.
+	Jitter float64
+	// RetryOn lists the error classes that are retried. Empty uses DefaultRetryOn.
+	RetryOn []ErrorKind
+	// RetryMutating allows retries for calls that are not safe to repeat.
+	RetryMutating bool
+}
+
+// Attempts returns the number of tries allowed for a request.
+func (r *RetryPolicy) Attempts(idempotent bool) int {
+	switch {
+	case !idempotent && !r.RetryMutating:
+		return 1
+	case r.MaxAttempts <= 0:
+		return DefaultRetryAttempts
+	default:
+		return r.MaxAttempts
+	}
+}
+
+// Retries reports whether an error class is retried by this policy.
+func (r *RetryPolicy) Retries(kind ErrorKind) bool {
+	if len(r.RetryOn) == 0 {
+		return slices.Contains(DefaultRetryOn, kind)
+	}
+
+	return slices.Contains(r.RetryOn, kind)
+}
+
+// Delay returns how long to wait after a failed attempt (starting at 1) before trying again.
+func (r *RetryPolicy) Delay(attempt int) time.Duration {
+	base, maxDelay, jitter := r.BaseDelay.Duration, r.MaxDelay.Duration, r.Jitter
+
+	if base <= 0 {
+		base = DefaultRetryDelay
+	}
+
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+
+	if jitter == 0 {
+		jitter = DefaultRetryJitter
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	delay = min(delay, maxDelay)
+
+	if jitter > 0 {
+		delay -= time.Duration(rand.Float64() * min(jitter, 1) * float64(delay)) //nolint:gosec // not crypto.
+	}
+
+	return delay
+}
+
+// Idempotent reports whether a request may be repeated without side effects.
+func Idempotent(method, apiPath string) bool {
+	return method == http.MethodGet && !nonIdempotent[apiPath]
+}
+
+// retry calls try until it succeeds, returns an error the policy does not retry,
+// runs out of attempts, or ctx ends. last is true on the final attempt.
+// The context passed to try carries the attempt number for observers.
+func (s *Config) retry(ctx context.Context, idempotent bool, try func(ctx context.Context, last bool) error) error {
+	attempts := s.Retry.Attempts(idempotent)
+
+	for attempt := 1; ; attempt++ {
+		err := try(context.WithValue(ctx, attemptKey{}, attempt), attempt >= attempts)
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !s.Retry.Retries(KindOf(err)) {
+			return err
+		}
+
+		timer := time.NewTimer(s.Retry.Delay(attempt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryStatus converts a response with a retryable status into an APIError, so it can be retried.
+// The response is returned as-is on the last attempt, or if the policy does not retry its status.
+func (s *Config) retryStatus(resp *http.Response, apiPath string, last bool) error {
+	kind := StatusKind(resp.StatusCode)
+	if last || kind == "" || !s.Retry.Retries(kind) {
+		return nil
+	}
+
+	return s.ResponseError(resp, apiPath)
+}
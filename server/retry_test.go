@@ -0,0 +1,156 @@
+package server_test
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2/server"
+)
+
+// flakyHandler drops the connection for the first fail requests to each path, then calls next.
+func flakyHandler(t *testing.T, fail int32, next http.HandlerFunc) (http.HandlerFunc, *atomic.Int32) {
+	t.Helper()
+
+	calls := &atomic.Int32{}
+
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) > fail {
+			next(resp, req)
+			return
+		}
+
+		conn, _, err := resp.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijacking connection: %v", err)
+			return
+		}
+
+		_ = conn.Close()
+	}, calls
+}
+
+func retryConfig(policy server.RetryPolicy) *server.Config {
+	policy.BaseDelay = server.Duration{time.Millisecond}
+
+	return &server.Config{URL: urlStr, Timeout: server.Duration{time.Second}, Retry: policy}
+}
+
+func TestRetryIdempotentGET(t *testing.T) {
+	t.Parallel()
+
+	handler, calls := flakyHandler(t, 2, func(resp http.ResponseWriter, _ *http.Request) {
+		_, _ = resp.Write([]byte("<root><value>ok</value></root>"))
+	})
+
+	httpClient, fakeServer := testingHTTPClient(handler)
+	defer fakeServer.Close()
+
+	config := retryConfig(server.RetryPolicy{})
+	config.Client = httpClient
+
+	var val struct {
+		Value string `xml:"value"`
+	}
+
+	require.NoError(t, config.GetXML("++systemInfo", nil, &val))
+	require.Equal(t, "ok", val.Value)
+	require.EqualValues(t, 3, calls.Load(), "two dropped connections and one success")
+}
+
+func TestRetryMutatingOptIn(t *testing.T) {
+	t.Parallel()
+
+	handler, calls := flakyHandler(t, 1, func(resp http.ResponseWriter, _ *http.Request) {
+		_, _ = resp.Write([]byte("OK"))
+	})
+
+	httpClient, fakeServer := testingHTTPClient(handler)
+	defer fakeServer.Close()
+
+	config := retryConfig(server.RetryPolicy{})
+	config.Client = httpClient
+
+	err := config.SimpleReq("++triggermd", url.Values{}, 1)
+	require.ErrorIs(t, err, server.ErrTransport)
+	require.EqualValues(t, 1, calls.Load(), "triggers must not retry by default")
+
+	calls.Store(0)
+
+	config.Retry.RetryMutating = true
+	require.NoError(t, config.SimpleReq("++ptz/command", url.Values{}, 1))
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestRetryStatusRules(t *testing.T) {
+	t.Parallel()
+
+	calls := &atomic.Int32{}
+	handler := http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+
+		if req.URL.Path == "/++busy" {
+			http.Error(resp, "busy", http.StatusServiceUnavailable)
+		} else {
+			http.NotFound(resp, req)
+		}
+	})
+
+	httpClient, fakeServer := testingHTTPClient(handler)
+	defer fakeServer.Close()
+
+	config := retryConfig(server.RetryPolicy{MaxAttempts: 4})
+	config.Client = httpClient
+
+	require.ErrorIs(t, config.SimpleReq("++busy", url.Values{}, 1), server.ErrServerBusy)
+	require.EqualValues(t, 4, calls.Load())
+
+	calls.Store(0)
+	require.ErrorIs(t, config.SimpleReq("++missing", url.Values{}, 1), server.ErrNotFound)
+	require.EqualValues(t, 1, calls.Load(), "not found is not retried")
+
+	calls.Store(0)
+
+	resp, err := config.Get("++busy", nil)
+	require.NoError(t, err, "Get returns the final response so the caller can inspect it")
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.EqualValues(t, 4, calls.Load())
+
+	calls.Store(0)
+
+	config.Retry.RetryOn = []server.ErrorKind{server.KindNotFound}
+	require.ErrorIs(t, config.SimpleReq("++busy", url.Values{}, 1), server.ErrServerBusy)
+	require.EqualValues(t, 1, calls.Load(), "busy is not in the custom RetryOn list")
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	t.Parallel()
+
+	policy := server.RetryPolicy{
+		BaseDelay: server.Duration{100 * time.Millisecond},
+		MaxDelay:  server.Duration{time.Second},
+		Jitter:    -1,
+	}
+
+	require.Equal(t, 100*time.Millisecond, policy.Delay(1))
+	require.Equal(t, 200*time.Millisecond, policy.Delay(2))
+	require.Equal(t, 800*time.Millisecond, policy.Delay(4))
+	require.Equal(t, time.Second, policy.Delay(10))
+
+	policy.Jitter = 0.5
+	for range 100 {
+		delay := policy.Delay(2)
+		require.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		require.LessOrEqual(t, delay, 200*time.Millisecond)
+	}
+
+	require.Equal(t, 1, policy.Attempts(false))
+	require.Equal(t, server.DefaultRetryAttempts, policy.Attempts(true))
+	require.False(t, server.Idempotent(http.MethodPost, "++settings-general"))
+	require.False(t, server.Idempotent(http.MethodGet, "++triggermd"))
+	require.True(t, server.Idempotent(http.MethodGet, "++ssControlMotionCapture"))
+}
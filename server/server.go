@@ -41,6 +41,10 @@ type Config struct {
 	// 0 or negative values fall back to DefaultJPEGRetries.
 	JPEGRetries int
 	VerifySSL   bool // Also only used if you do not provide an HTTP client.
+	// Retry controls retries for failed requests. The zero value retries idempotent GETs.
+	Retry RetryPolicy
+	// Observer, if set, is notified about every request. See NewSlogObserver and NewTracingObserver.
+	Observer Observer
 }
 
 // HTTPClient returns an http.Client with the configured timeout and SSL verification.
@@ -104,12 +108,26 @@ func (s *Config) JPEGTries() int {
 }
 
 // GetContextClient is the same as Get except you can pass in your own context and http Client.
-func (s *Config) GetContextClient( //nolint:cyclop // might make it less complicated later.
+// It makes a single attempt; the Retry policy is not applied.
+func (s *Config) GetContextClient(
 	ctx context.Context,
 	api string,
 	params url.Values,
 	client *http.Client,
-) (*http.Response, error) {
+) (resp *http.Response, err error) {
+	req, err := s.newGetRequest(ctx, api, params)
+	if err != nil {
+		return nil, err
+	}
+
+	req, info := s.startRequest(req, api, params)
+	defer func() { s.endRequest(req.Context(), info, resp, err) }()
+
+	return s.send(client, req, api)
+}
+
+// newGetRequest builds a GET request to SecuritySpy. It adds the auth and format parameters to params.
+func (s *Config) newGetRequest(ctx context.Context, api string, params url.Values) (*http.Request, error) {
 	if params == nil {
 		params = make(url.Values)
 	}
@@ -134,24 +152,50 @@ func (s *Config) GetContextClient( //nolint:cyclop // might make it less complic
 
 	req.URL.RawQuery = params.Encode()
 
+	return req, nil
+}
+
+// send executes a request and wraps transport failures in an APIError.
+func (s *Config) send(client *http.Client, req *http.Request, apiPath string) (*http.Response, error) {
 	resp, err := client.Do(req)
 	if err != nil {
-		return resp, fmt.Errorf("http request: %w", err)
+		return resp, s.newAPIError(req, apiPath, KindTransport, 0, nil, err)
 	}
 
 	return resp, nil
 }
 
+// client returns the configured http client, or a new one from HTTPClient if none is set.
+// The field is not written here, so concurrent requests do not race on it.
+func (s *Config) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+
+	return s.HTTPClient()
+}
+
 // GetContext is the same as Get except you can pass in your own context.
 func (s *Config) GetContext(ctx context.Context, apiPath string, params url.Values) (*http.Response, error) {
-	if s.Client == nil {
-		s.Client = s.HTTPClient()
+	var resp *http.Response
+
+	err := s.retry(ctx, Idempotent(http.MethodGet, apiPath), func(ctx context.Context, last bool) error {
+		var err error
+		if resp, err = s.GetContextClient(ctx, apiPath, params, s.client()); err != nil {
+			return err
+		}
+
+		return s.retryStatus(resp, apiPath, last)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return s.GetContextClient(ctx, apiPath, params, s.Client)
+	return resp, nil
 }
 
 // GetClient is the same as Get except you can pass in your own http Client.
+// It makes a single attempt; the Retry policy is not applied.
 func (s *Config) GetClient(apiPath string, params url.Values, client *http.Client) (*http.Response, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.TimeoutDur())
 	defer cancel()
@@ -160,15 +204,12 @@ func (s *Config) GetClient(apiPath string, params url.Values, client *http.Clien
 }
 
 // Get is a helper function that formats the http request to SecuritySpy.
+// Failed requests are retried according to the Retry policy.
 func (s *Config) Get(apiPath string, params url.Values) (*http.Response, error) {
-	if s.Client == nil {
-		s.Client = s.HTTPClient()
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), s.TimeoutDur())
 	defer cancel()
 
-	return s.GetContextClient(ctx, apiPath, params, s.Client)
+	return s.GetContext(ctx, apiPath, params)
 }
 
 // Post is a helper function that formats the http request to SecuritySpy.
@@ -180,9 +221,10 @@ func (s *Config) Post(apiPath string, params url.Values, body io.ReadCloser) ([]
 }
 
 // PostContext is a helper function that formats the http request to SecuritySpy.
+// It is never retried because the body cannot be replayed.
 func (s *Config) PostContext(
 	ctx context.Context, apiPath string, params url.Values, body io.ReadCloser,
-) ([]byte, error) {
+) (reply []byte, err error) {
 	if params == nil {
 		params = make(url.Values)
 	}
@@ -201,21 +243,23 @@ func (s *Config) PostContext(
 	}
 
 	req.URL.RawQuery = params.Encode()
+	req, info := s.startRequest(req, apiPath, params)
 
-	resp, err := s.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("getting body: %w", err)
+	var resp *http.Response
+	defer func() { s.endRequest(req.Context(), info, resp, err) }()
+
+	if resp, err = s.send(s.client(), req, apiPath); err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	reply, err := io.ReadAll(resp.Body)
+	reply, err = io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading body: %w", err)
+		return nil, s.newAPIError(req, apiPath, KindTransport, resp.StatusCode, nil, err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return reply, fmt.Errorf("request failed (%v): %v (status: %v/%v): %w",
-			s.Username, s.URL+apiPath, resp.StatusCode, resp.Status, err)
+	if kind := StatusKind(resp.StatusCode); kind != "" {
+		return reply, s.newAPIError(req, apiPath, kind, resp.StatusCode, reply, nil)
 	}
 
 	return reply, nil
@@ -230,22 +274,36 @@ func (s *Config) GetXML(apiPath string, params url.Values, val any) error {
 }
 
 // GetXMLContext returns raw http body, so it can be unmarshaled into an xml struct.
+// Failed requests are retried according to the Retry policy.
 func (s *Config) GetXMLContext(ctx context.Context, apiPath string, params url.Values, val any) error {
-	resp, err := s.GetContext(ctx, apiPath, params)
+	return s.retry(ctx, Idempotent(http.MethodGet, apiPath), func(ctx context.Context, _ bool) error {
+		return s.getXMLOnce(ctx, apiPath, params, val)
+	})
+}
+
+func (s *Config) getXMLOnce(ctx context.Context, apiPath string, params url.Values, val any) (err error) {
+	req, err := s.newGetRequest(ctx, apiPath, params)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
+	req, info := s.startRequest(req, apiPath, params)
+
+	var resp *http.Response
+	defer func() { s.endRequest(req.Context(), info, resp, err) }()
+
+	if resp, err = s.send(s.client(), req, apiPath); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-		return fmt.Errorf("request failed (%v): %v (status: %v/%v): %w: %s",
-			s.Username, s.URL+apiPath, resp.StatusCode, resp.Status, err, string(body))
+	if kind := StatusKind(resp.StatusCode); kind != "" {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, MaxErrorBody))
+		return s.newAPIError(req, apiPath, kind, resp.StatusCode, body, nil)
 	}
 
 	if err = xml.NewDecoder(resp.Body).Decode(val); err != nil {
-		return fmt.Errorf("reading body: %w", err)
+		return s.newAPIError(req, apiPath, KindParse, resp.StatusCode, nil, err)
 	}
 
 	return nil
@@ -260,27 +318,55 @@ func (s *Config) SimpleReq(apiURI string, params url.Values, cameraNum int) erro
 }
 
 // SimpleReqContext performes HTTP req, checks for OK at end of output.
+// Failed requests are retried according to the Retry policy; ++triggermd
+// and ++ptz/command only retry if RetryMutating is set.
 func (s *Config) SimpleReqContext(ctx context.Context, apiURI string, params url.Values, cameraNum int) error {
 	if cameraNum >= 0 {
 		params.Set("cameraNum", strconv.Itoa(cameraNum))
 	}
 
-	resp, err := s.GetContext(ctx, apiURI, params)
+	return s.retry(ctx, Idempotent(http.MethodGet, apiURI), func(ctx context.Context, _ bool) error {
+		return s.simpleReqOnce(ctx, apiURI, params)
+	})
+}
+
+func (s *Config) simpleReqOnce(ctx context.Context, apiURI string, params url.Values) (err error) {
+	req, err := s.newGetRequest(ctx, apiURI, params)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return ErrNotFound
+	req, info := s.startRequest(req, apiURI, params)
+
+	var resp *http.Response
+	defer func() { s.endRequest(req.Context(), info, resp, err) }()
+
+	if resp, err = s.send(s.client(), req, apiURI); err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
+	return s.checkReply(resp, apiURI)
+}
+
+// checkReply reads a command response and returns an APIError unless it is a 200 ending with OK.
+// Non-200 replies also wrap ErrCmdNotOK, so callers that only check for it keep working.
+func (s *Config) checkReply(resp *http.Response, apiPath string) error {
 	body, err := io.ReadAll(resp.Body)
-	if err != nil || !replyOK(body) {
-		return ErrCmdNotOK
+	if err != nil {
+		return s.newAPIError(resp.Request, apiPath, KindTransport, resp.StatusCode, nil, err)
 	}
 
-	return nil
+	switch kind := StatusKind(resp.StatusCode); {
+	case kind == KindNotFound:
+		return s.newAPIError(resp.Request, apiPath, kind, resp.StatusCode, body, nil)
+	case kind != "":
+		return s.newAPIError(resp.Request, apiPath, kind, resp.StatusCode, body, ErrCmdNotOK)
+	case !replyOK(body):
+		return s.newAPIError(resp.Request, apiPath, KindCommand, resp.StatusCode, body, nil)
+	default:
+		return nil
+	}
 }
 
 // replyOK reports whether a SecuritySpy response body indicates success.
@@ -295,7 +381,14 @@ func replyOK(body []byte) bool {
 }
 
 // PostFormContext POSTs application/x-www-form-urlencoded data and requires an OK reply.
+// It is only retried if the Retry policy has RetryMutating set.
 func (s *Config) PostFormContext(ctx context.Context, apiPath string, form url.Values) error {
+	return s.retry(ctx, Idempotent(http.MethodPost, apiPath), func(ctx context.Context, _ bool) error {
+		return s.postFormOnce(ctx, apiPath, form)
+	})
+}
+
+func (s *Config) postFormOnce(ctx context.Context, apiPath string, form url.Values) (err error) {
 	if form == nil {
 		form = make(url.Values)
 	}
@@ -305,10 +398,6 @@ func (s *Config) PostFormContext(ctx context.Context, apiPath string, form url.V
 		params.Set("auth", s.Password)
 	}
 
-	if s.Client == nil {
-		s.Client = s.HTTPClient()
-	}
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL+apiPath, strings.NewReader(form.Encode()))
 	if err != nil {
 		return fmt.Errorf("http.NewRequest(): %w", err)
@@ -316,27 +405,17 @@ func (s *Config) PostFormContext(ctx context.Context, apiPath string, form url.V
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.URL.RawQuery = params.Encode()
+	req, info := s.startRequest(req, apiPath, form)
 
-	resp, err := s.Client.Do(req)
-	if err != nil {
-		return fmt.Errorf("http request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("reading body: %w", err)
-	}
-
-	if resp.StatusCode == http.StatusNotFound {
-		return ErrNotFound
-	}
+	var resp *http.Response
+	defer func() { s.endRequest(req.Context(), info, resp, err) }()
 
-	if resp.StatusCode != http.StatusOK || !replyOK(body) {
-		return ErrCmdNotOK
+	if resp, err = s.send(s.client(), req, apiPath); err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	return s.checkReply(resp, apiPath)
 }
 
 // PostForm POSTs form values with the default timeout.
@@ -234,6 +234,26 @@ func testingHTTPClient(handler http.Handler) (*http.Client, *httptest.Server) {
 	return client, fakeServer
 }
 
+func TestDefaultClientConcurrent(t *testing.T) {
+	t.Parallel()
+
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		_, _ = resp.Write([]byte("OK"))
+	}))
+	defer fakeServer.Close()
+
+	config := &server.Config{URL: fakeServer.URL + "/", Timeout: server.Duration{time.Second}}
+	errs := make(chan error, 4)
+
+	for range cap(errs) {
+		go func() { errs <- config.SimpleReq("++ok", nil, -1) }()
+	}
+
+	for range cap(errs) {
+		require.NoError(t, <-errs, "requests without a Client share no lazily set field")
+	}
+}
+
 /*
 func TestGetXML(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,75 @@
+package server
+
+import "context"
+
+// Tracer starts spans for the tracing observer. It mirrors the shape of an OpenTelemetry
+// tracer without importing it; wrap your tracer in a few lines to satisfy it:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, server.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is the part of a trace span the tracing observer uses.
+type Span interface {
+	// SetAttribute records a string, int or bool attribute on the span.
+	SetAttribute(key string, value any)
+	// RecordError marks the span failed with err.
+	RecordError(err error)
+	End()
+}
+
+// TracingObserver starts a span named "securityspy.<operation>" for every request.
+// Attributes use OpenTelemetry HTTP semantic convention names where one exists.
+type TracingObserver struct {
+	tracer Tracer
+}
+
+// NewTracingObserver returns an observer that creates a span per request with tracer.
+func NewTracingObserver(tracer Tracer) *TracingObserver {
+	return &TracingObserver{tracer: tracer}
+}
+
+type spanKey struct{}
+
+// RequestStart starts a span and stores it in the returned context.
+func (o *TracingObserver) RequestStart(ctx context.Context, info *RequestInfo) context.Context {
+	ctx, span := o.tracer.Start(ctx, "securityspy."+info.Operation)
+	span.SetAttribute("http.request.method", info.Method)
+	span.SetAttribute("url.full", info.URL)
+	span.SetAttribute("securityspy.endpoint", info.Endpoint)
+	span.SetAttribute("http.request.resend_count", info.Attempt-1)
+
+	if info.Camera >= 0 {
+		span.SetAttribute("securityspy.camera", info.Camera)
+	}
+
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// RequestEnd records the status and error, and ends the span.
+func (o *TracingObserver) RequestEnd(ctx context.Context, info *RequestInfo) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+
+	if info.Status != 0 {
+		span.SetAttribute("http.response.status_code", info.Status)
+	}
+
+	if info.Err != nil {
+		if kind := KindOf(info.Err); kind != "" {
+			span.SetAttribute("error.type", string(kind))
+		}
+
+		span.RecordError(info.Err)
+	}
+
+	span.End()
+}
@@ -13,7 +13,7 @@ var ErrCameraNumRequired = errors.New("cameraNum required")
 // GetGeneralSettings fetches ++settings-general.
 func (s *Server) GetGeneralSettings() (*GeneralSettings, error) {
 	var val GeneralSettings
-	if err := s.GetXML("++settings-general", nil, &val); err != nil {
+	if err := s.getSettings("++settings-general", nil, &val); err != nil {
 		return nil, fmt.Errorf("getting general settings: %w", err)
 	}
 
@@ -22,7 +22,7 @@ func (s *Server) GetGeneralSettings() (*GeneralSettings, error) {
 
 // SetGeneralSettings posts form fields to ++settings-general (partial update).
 func (s *Server) SetGeneralSettings(form url.Values) error {
-	if err := s.PostForm("++settings-general", form); err != nil {
+	if err := s.postSettings("++settings-general", form); err != nil {
 		return fmt.Errorf("setting general settings: %w", err)
 	}
 
@@ -32,7 +32,7 @@ func (s *Server) SetGeneralSettings(form url.Values) error {
 // GetDisplaySettings fetches ++settings-display.
 func (s *Server) GetDisplaySettings() (*DisplaySettings, error) {
 	var val DisplaySettings
-	if err := s.GetXML("++settings-display", nil, &val); err != nil {
+	if err := s.getSettings("++settings-display", nil, &val); err != nil {
 		return nil, fmt.Errorf("getting display settings: %w", err)
 	}
 
@@ -41,7 +41,7 @@ func (s *Server) GetDisplaySettings() (*DisplaySettings, error) {
 
 // SetDisplaySettings posts form fields to ++settings-display (partial update).
 func (s *Server) SetDisplaySettings(form url.Values) error {
-	if err := s.PostForm("++settings-display", form); err != nil {
+	if err := s.postSettings("++settings-display", form); err != nil {
 		return fmt.Errorf("setting display settings: %w", err)
 	}
 
@@ -51,7 +51,7 @@ func (s *Server) SetDisplaySettings(form url.Values) error {
 // GetStorageSettings fetches ++settings-storage.
 func (s *Server) GetStorageSettings() (*StorageSettings, error) {
 	var val StorageSettings
-	if err := s.GetXML("++settings-storage", nil, &val); err != nil {
+	if err := s.getSettings("++settings-storage", nil, &val); err != nil {
 		return nil, fmt.Errorf("getting storage settings: %w", err)
 	}
 
@@ -60,7 +60,7 @@ func (s *Server) GetStorageSettings() (*StorageSettings, error) {
 
 // SetStorageSettings posts form fields to ++settings-storage (partial update).
 func (s *Server) SetStorageSettings(form url.Values) error {
-	if err := s.PostForm("++settings-storage", form); err != nil {
+	if err := s.postSettings("++settings-storage", form); err != nil {
 		return fmt.Errorf("setting storage settings: %w", err)
 	}
 
@@ -70,7 +70,7 @@ func (s *Server) SetStorageSettings(form url.Values) error {
 // GetCompressionSettings fetches ++settings-compression.
 func (s *Server) GetCompressionSettings() (*CompressionSettings, error) {
 	var val CompressionSettings
-	if err := s.GetXML("++settings-compression", nil, &val); err != nil {
+	if err := s.getSettings("++settings-compression", nil, &val); err != nil {
 		return nil, fmt.Errorf("getting compression settings: %w", err)
 	}
 
@@ -79,7 +79,7 @@ func (s *Server) GetCompressionSettings() (*CompressionSettings, error) {
 
 // SetCompressionSettings posts form fields to ++settings-compression (partial update).
 func (s *Server) SetCompressionSettings(form url.Values) error {
-	if err := s.PostForm("++settings-compression", form); err != nil {
+	if err := s.postSettings("++settings-compression", form); err != nil {
 		return fmt.Errorf("setting compression settings: %w", err)
 	}
 
@@ -89,7 +89,7 @@ func (s *Server) SetCompressionSettings(form url.Values) error {
 // GetEmailSettings fetches ++settings-email.
 func (s *Server) GetEmailSettings() (*EmailSettings, error) {
 	var val EmailSettings
-	if err := s.GetXML("++settings-email", nil, &val); err != nil {
+	if err := s.getSettings("++settings-email", nil, &val); err != nil {
 		return nil, fmt.Errorf("getting email settings: %w", err)
 	}
 
@@ -98,7 +98,7 @@ func (s *Server) GetEmailSettings() (*EmailSettings, error) {
 
 // SetEmailSettings posts form fields to ++settings-email (partial update).
 func (s *Server) SetEmailSettings(form url.Values) error {
-	if err := s.PostForm("++settings-email", form); err != nil {
+	if err := s.postSettings("++settings-email", form); err != nil {
 		return fmt.Errorf("setting email settings: %w", err)
 	}
 
@@ -108,7 +108,7 @@ func (s *Server) SetEmailSettings(form url.Values) error {
 // GetWebSettings fetches ++settings-web.
 func (s *Server) GetWebSettings() (*WebSettings, error) {
 	var val WebSettings
-	if err := s.GetXML("++settings-web", nil, &val); err != nil {
+	if err := s.getSettings("++settings-web", nil, &val); err != nil {
 		return nil, fmt.Errorf("getting web settings: %w", err)
 	}
 
@@ -117,7 +117,7 @@ func (s *Server) GetWebSettings() (*WebSettings, error) {
 
 // SetWebSettings posts form fields to ++settings-web (partial update).
 func (s *Server) SetWebSettings(form url.Values) error {
-	if err := s.PostForm("++settings-web", form); err != nil {
+	if err := s.postSettings("++settings-web", form); err != nil {
 		return fmt.Errorf("setting web settings: %w", err)
 	}
 
@@ -130,7 +130,7 @@ func (s *Server) GetCameraSettings(cameraNum int) (*CameraSettings, error) {
 	params.Set("cameraNum", strconv.Itoa(cameraNum))
 
 	var val CameraSettings
-	if err := s.GetXML("++settings-cameras", params, &val); err != nil {
+	if err := s.getSettings("++settings-cameras", params, &val); err != nil {
 		return nil, fmt.Errorf("getting camera settings: %w", err)
 	}
 
@@ -144,9 +144,27 @@ func (s *Server) SetCameraSettings(form url.Values) error {
 		return ErrCameraNumRequired
 	}
 
-	if err := s.PostForm("++settings-cameras", form); err != nil {
+	if err := s.postSettings("++settings-cameras", form); err != nil {
 		return fmt.Errorf("setting camera settings: %w", err)
 	}
 
 	return nil
 }
+
+// getSettings fetches a ++settings-* endpoint, or returns ErrUnsupported on servers older than v6.
+func (s *Server) getSettings(apiPath string, params url.Values, val any) error {
+	if !s.Capabilities().Settings {
+		return s.unsupported(apiPath)
+	}
+
+	return s.GetXML(apiPath, params, val) //nolint:wrapcheck // callers wrap it.
+}
+
+// postSettings posts to a ++settings-* endpoint, or returns ErrUnsupported on servers older than v6.
+func (s *Server) postSettings(apiPath string, form url.Values) error {
+	if !s.Capabilities().Settings {
+		return s.unsupported(apiPath)
+	}
+
+	return s.PostForm(apiPath, form) //nolint:wrapcheck // callers wrap it.
+}
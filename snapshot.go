@@ -0,0 +1,77 @@
+package securityspy
+
+// Snapshot is an immutable view of a server's inventory from one Refresh: server info,
+// schedules, cameras and groups. Every Refresh builds a new snapshot and swaps it in
+// atomically, so readers get a consistent view without locking. Get the current one with
+// Server.Snapshot. Do not modify anything reachable from a snapshot.
+type Snapshot struct {
+	// Generation increases by one with every successful Refresh. It is 0 before the first one.
+	Generation uint64
+	// Info includes the server schedule, schedule preset and schedule override names.
+	Info    *ServerInfo
+	Cameras *Cameras
+	Groups  []*Group
+	// Changes lists the differences from the previous generation. Empty for generations 0 and 1.
+	Changes []Change
+}
+
+// Snapshot returns the inventory published by the most recent successful Refresh.
+// It never returns nil; before the first Refresh the snapshot is empty with Generation 0.
+func (s *Server) Snapshot() *Snapshot {
+	return s.snapshot.Load()
+}
+
+// Generation returns the snapshot generation this camera belongs to. Events keep the camera
+// that was current when they were parsed; compare this with Server.Snapshot().Generation
+// to find out if a newer Refresh replaced it.
+func (c *Camera) Generation() uint64 {
+	return c.generation
+}
+
+// newSnapshot turns a systemInfo reply into a snapshot, wiring cameras to the server
+// and filling in schedule names (systemInfo only provides IDs on cameras).
+func (s *Server) newSnapshot(sysInfo *systemInfo, generation uint64) *Snapshot {
+	info := sysInfo.Server
+	if info == nil {
+		info = &ServerInfo{}
+	}
+
+	// Point all the unmarshalled data into an exported struct. Better-formatted data.
+	info.ServerSchedules = sysInfo.schedules()
+	info.SchedulePresets = sysInfo.schedulePresets()
+	info.ScheduleOverrides = sysInfo.scheduleOverrides()
+
+	snap := &Snapshot{
+		Generation: generation,
+		Info:       info,
+		Cameras:    &Cameras{cameras: sysInfo.cameras(), server: s},
+		Groups:     sysInfo.GroupList.Groups,
+	}
+
+	for _, cam := range snap.Cameras.cameras {
+		cam.server = s
+		cam.generation = generation
+
+		if cam.PTZ != nil {
+			cam.PTZ.camera = cam
+		}
+
+		cam.ScheduleIDA.Name = nameOr(info.ServerSchedules, cam.ScheduleIDA)
+		cam.ScheduleIDCC.Name = nameOr(info.ServerSchedules, cam.ScheduleIDCC)
+		cam.ScheduleIDMC.Name = nameOr(info.ServerSchedules, cam.ScheduleIDMC)
+		cam.ScheduleOverrideA.Name = nameOr(info.ScheduleOverrides, cam.ScheduleOverrideA)
+		cam.ScheduleOverrideCC.Name = nameOr(info.ScheduleOverrides, cam.ScheduleOverrideCC)
+		cam.ScheduleOverrideMC.Name = nameOr(info.ScheduleOverrides, cam.ScheduleOverrideMC)
+	}
+
+	return snap
+}
+
+// nameOr returns the name for a schedule ID, or the name it already has if the ID is unknown.
+func nameOr(names map[int]string, schedule CameraSchedule) string {
+	if name, ok := names[schedule.ID]; ok {
+		return name
+	}
+
+	return schedule.Name
+}
@@ -0,0 +1,79 @@
+package securityspy_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+	"golift.io/securityspy/v2/server"
+)
+
+func TestSnapshotGenerations(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	config := fake.Config()
+	config.Retry = server.RetryPolicy{MaxAttempts: 1}
+	sspy := securityspy.NewMust(config)
+
+	empty := sspy.Snapshot()
+	require.NotNil(t, empty)
+	require.Zero(t, empty.Generation)
+	require.Empty(t, empty.Cameras.All())
+
+	require.NoError(t, sspy.Refresh())
+
+	first := sspy.Snapshot()
+	require.Equal(t, uint64(1), first.Generation)
+	require.Same(t, first.Cameras, sspy.Cameras, "legacy fields mirror the snapshot")
+
+	door := first.Cameras.ByNum(3)
+	require.NotNil(t, door)
+	require.Equal(t, uint64(1), door.Generation())
+
+	fake.UpdateCamera(3, func(cam *securityspytest.Camera) { cam.Name = "Front Door" })
+	require.NoError(t, sspy.Refresh())
+
+	second := sspy.Snapshot()
+	require.Equal(t, uint64(2), second.Generation)
+	require.Equal(t, "Front Door", second.Cameras.ByNum(3).Name)
+	require.Equal(t, uint64(2), second.Cameras.ByNum(3).Generation())
+	require.Equal(t, "Door", door.Name, "old snapshots are never modified")
+	require.Equal(t, "Door", first.Cameras.ByNum(3).Name)
+
+	fake.Handle("/++systemInfo", func(resp http.ResponseWriter, _ *http.Request) {
+		http.Error(resp, "busy", http.StatusServiceUnavailable)
+	})
+	require.Error(t, sspy.Refresh())
+	require.Same(t, second, sspy.Snapshot(), "a failed refresh keeps the last snapshot")
+}
+
+func TestSnapshotConcurrentRefresh(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy := securityspy.NewMust(fake.Config())
+	require.NoError(t, sspy.Refresh())
+
+	var wg sync.WaitGroup
+
+	for range 4 {
+		wg.Go(func() { _ = sspy.Refresh() })
+		wg.Go(func() {
+			snap := sspy.Snapshot()
+			for _, cam := range snap.Cameras.All() {
+				require.Equal(t, snap.Generation, cam.Generation())
+			}
+		})
+	}
+
+	wg.Wait()
+	require.Equal(t, uint64(5), sspy.Snapshot().Generation)
+}
@@ -0,0 +1,194 @@
+package securityspy
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// String returns the state name.
+func (s StreamState) String() string {
+	switch s {
+	case StreamStopped:
+		return "stopped"
+	case StreamConnecting:
+		return "connecting"
+	case StreamConnected:
+		return "connected"
+	case StreamBackoff:
+		return "backing off"
+	default:
+		return fmt.Sprintf("StreamState(%d)", int(s))
+	}
+}
+
+// State returns the current state of the event stream watcher.
+func (e *Events) State() StreamState {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	return e.stats.State
+}
+
+// Stats returns the watcher state and statistics for the current event stream connection.
+func (e *Events) Stats() StreamStats {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	return e.stats
+}
+
+// WaitConnected blocks until the event stream is connected or ctx is done.
+// It returns right away if the stream is already connected. It returns
+// ErrStreamStopped if Watch has not been called, or as soon as Stop is called.
+func (e *Events) WaitConnected(ctx context.Context) error {
+	for {
+		e.stateMu.Lock()
+		state, changed := e.stats.State, e.stateChanged()
+		e.stateMu.Unlock()
+
+		switch state { //nolint:exhaustive // other states keep waiting.
+		case StreamConnected:
+			return nil
+		case StreamStopped:
+			return ErrStreamStopped
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for event stream: %w", ctx.Err())
+		}
+	}
+}
+
+// SubscribeState returns a channel that receives every watcher state change.
+// Changes are dropped if the channel is full, so pick a buffer size to match your consumer.
+// Stop closes the channel when closeChans is true.
+func (e *Events) SubscribeState(buffer int) <-chan StreamStateChange {
+	sub := make(chan StreamStateChange, buffer)
+
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	if e.stateSubs == nil {
+		e.stateSubs = make(map[chan StreamStateChange]struct{})
+	}
+
+	e.stateSubs[sub] = struct{}{}
+
+	return sub
+}
+
+// UnsubscribeState stops and closes a channel returned by SubscribeState.
+func (e *Events) UnsubscribeState(sub <-chan StreamStateChange) {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	for ch := range e.stateSubs {
+		if ch == sub {
+			close(ch)
+			delete(e.stateSubs, ch)
+		}
+	}
+}
+
+// closeStateSubs closes every channel from SubscribeState.
+func (e *Events) closeStateSubs() {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	for ch := range e.stateSubs {
+		close(ch)
+		delete(e.stateSubs, ch)
+	}
+}
+
+// stateChanged returns the channel that closes on the next state change. Call with stateMu held.
+func (e *Events) stateChanged() chan struct{} {
+	if e.stateChange == nil {
+		e.stateChange = make(chan struct{})
+	}
+
+	return e.stateChange
+}
+
+// setState moves the watcher to a new state and notifies subscribers.
+// A non-nil err is recorded as the last error.
+func (e *Events) setState(state StreamState, err error) {
+	now := time.Now()
+
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	if err != nil {
+		e.stats.LastError = err
+		e.stats.LastErrorAt = now
+	}
+
+	from := e.stats.State
+	if from == state {
+		return
+	}
+
+	e.stats.State = state
+
+	if state != StreamBackoff {
+		e.stats.NextRetry = time.Time{}
+	}
+
+	switch state {
+	case StreamConnected:
+		e.stats.Connected = now
+		e.stats.Events = 0
+		e.stats.LastEvent = time.Time{}
+		e.stats.Failures = 0
+		e.stats.Connections++
+	case StreamBackoff, StreamStopped:
+		e.stats.Connected = time.Time{}
+	case StreamConnecting:
+	}
+
+	close(e.stateChanged())
+	e.stateChange = make(chan struct{})
+
+	for sub := range e.stateSubs {
+		select {
+		case sub <- StreamStateChange{From: from, To: state, Time: now, Err: err}:
+		default:
+		}
+	}
+}
+
+// backoff waits before the next connection attempt. It returns false if ctx ends first.
+// dialFailed counts err as a failed connection attempt.
+func (e *Events) backoff(ctx context.Context, delay time.Duration, err error, dialFailed bool) bool {
+	e.stateMu.Lock()
+	e.stats.NextRetry = time.Now().Add(delay)
+
+	if dialFailed {
+		e.stats.Failures++
+	}
+	e.stateMu.Unlock()
+
+	e.setState(StreamBackoff, err)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// received counts an event read from the current connection.
+func (e *Events) received() {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+
+	e.stats.Events++
+	e.stats.LastEvent = time.Now()
+}
@@ -0,0 +1,50 @@
+package securityspy
+
+import "time"
+
+const (
+	// DefaultMaxRetryInterval caps the delay between event stream connection attempts
+	// when Events.MaxRetryInterval is 0.
+	DefaultMaxRetryInterval = 2 * time.Minute
+	// DefaultKeepAliveTimeout is used when Events.KeepAliveTimeout is 0.
+	// It is three keep-alive intervals.
+	DefaultKeepAliveTimeout = 30 * time.Second
+)
+
+// StreamState is the state of the event stream watcher started by Events.Watch.
+type StreamState int
+
+// These are the event stream watcher states. The zero value is StreamStopped.
+const (
+	// StreamStopped means Watch has not been called, or Stop was called.
+	StreamStopped StreamState = iota
+	// StreamConnecting means a connection attempt is in progress.
+	StreamConnecting
+	// StreamConnected means the event stream is connected and reading events.
+	StreamConnected
+	// StreamBackoff means the last connection failed or closed and the watcher
+	// is waiting before it tries again. StreamStats.NextRetry says when.
+	StreamBackoff
+)
+
+// StreamStateChange is sent to channels from Events.SubscribeState when the watcher changes state.
+type StreamStateChange struct {
+	From StreamState
+	To   StreamState
+	Time time.Time
+	// Err is the dial or read error that caused a change to StreamBackoff.
+	Err error
+}
+
+// StreamStats describes the event stream watcher and its current connection, from Events.Stats.
+type StreamStats struct {
+	State       StreamState
+	Connected   time.Time // When the current connection was made. Zero if not connected.
+	Events      int       // Events read on the current connection, including keep-alives.
+	LastEvent   time.Time // When the last event on the current connection was read.
+	LastError   error     // Error from the last failed dial or closed connection.
+	LastErrorAt time.Time // When LastError happened.
+	Failures    int       // Failed connection attempts since the last successful connection.
+	Connections int       // Successful connections since the Events were created.
+	NextRetry   time.Time // When the next connection attempt happens. Zero unless backing off.
+}
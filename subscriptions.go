@@ -0,0 +1,86 @@
+package securityspy
+
+import (
+	"context"
+	"slices"
+)
+
+// Subscribe returns a channel that receives the events that match filter. The channel
+// closes when ctx ends, when it is passed to Unsubscribe, or when Stop is called with
+// closeChans. Like bound channels, events are queued while the channel is full, and
+// dropped once DefaultBindQueue events are waiting.
+func (e *Events) Subscribe(ctx context.Context, filter Filter) <-chan Event {
+	sub := make(chan Event, SubscribeBuffer)
+	binding := &Binding{channel: sub, sub: true, filter: func(event *Event) bool { return filter.match(event, e.server) }}
+	e.bind(EventAllEvents, binding, nil)
+
+	release := context.AfterFunc(ctx, binding.Unbind)
+
+	binding.mu.Lock()
+	binding.release = release
+	binding.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe stops and closes a channel returned by Subscribe.
+func (e *Events) Unsubscribe(sub <-chan Event) {
+	e.unbind(func(binding *Binding) bool { return binding.sub && binding.channel == sub })
+}
+
+// match returns true if the event passes every filter that is set.
+func (f *Filter) match(event *Event, server *Server) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, event.Type) {
+		return false
+	}
+
+	if (len(f.Cameras) > 0 || len(f.CameraNames) > 0 || len(f.Groups) > 0) && !f.matchCamera(event.Camera, server) {
+		return false
+	}
+
+	if len(f.Reasons) > 0 && !slices.ContainsFunc(event.Reasons, func(r TriggerEvent) bool {
+		return slices.Contains(f.Reasons, r)
+	}) {
+		return false
+	}
+
+	return len(f.MinScores) == 0 || f.matchScores(event)
+}
+
+func (f *Filter) matchCamera(camera *Camera, server *Server) bool {
+	if camera == nil {
+		return false
+	}
+
+	if slices.Contains(f.Cameras, camera.Number) || slices.Contains(f.CameraNames, camera.Name) {
+		return true
+	}
+
+	if len(f.Groups) == 0 || server == nil {
+		return false
+	}
+
+	for _, group := range server.Snapshot().Groups {
+		if slices.Contains(f.Groups, group.Name) && slices.Contains(group.CameraNumbers(), camera.Number) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *Filter) matchScores(event *Event) bool {
+	if event.Type != EventClassify {
+		return false
+	}
+
+	scores := event.scores()
+
+	for class, minimum := range f.MinScores {
+		if score, ok := scores[class]; ok && score >= minimum {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+package securityspy_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
+)
+
+func TestSubscribeFilters(t *testing.T) {
+	t.Parallel()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	porch := sspy.Events.Subscribe(ctx, securityspy.Filter{
+		Types:       []securityspy.EventType{securityspy.EventTriggerMotion},
+		CameraNames: []string{"Porch"},
+	})
+	humans := sspy.Events.Subscribe(ctx, securityspy.Filter{Groups: []string{"Base"}, MinScores: map[string]int{"HUMAN": 80}})
+	reasons := sspy.Events.Subscribe(ctx, securityspy.Filter{
+		Cameras: []int{3},
+		Reasons: []securityspy.TriggerEvent{securityspy.TriggerByHumanDetection},
+	})
+
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, time.Second)
+	defer waitCancel()
+	require.NoError(t, sspy.Events.WaitConnected(waitCtx))
+
+	fake.PushEvent(3, "TRIGGER_M", "1")
+	fake.PushEvent(2, "ARM_M")
+	fake.PushEvent(3, "CLASSIFY", "HUMAN", "40")
+	fake.PushEvent(3, "TRIGGER_M", "129") // motion + human.
+	fake.PushEvent(2, "CLASSIFY", "HUMAN", "95")
+	fake.PushEvent(2, "TRIGGER_M", "1")
+
+	next := func(sub <-chan securityspy.Event) securityspy.Event {
+		t.Helper()
+
+		select {
+		case event := <-sub:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+		}
+
+		return securityspy.Event{}
+	}
+
+	require.Equal(t, 6, next(porch).ID)
+	require.Equal(t, 5, next(humans).ID)
+	require.Equal(t, 4, next(reasons).ID)
+
+	sspy.Events.UnbindChan(securityspy.EventAllEvents)
+	require.Len(t, sspy.Events.BindingStats(), 3, "UnbindChan does not remove subscriptions")
+	sspy.Events.Unsubscribe(porch)
+	cancel()
+
+	for _, sub := range []<-chan securityspy.Event{porch, humans, reasons} {
+		select {
+		case event, ok := <-sub:
+			require.False(t, ok, "nothing else matches: %s", event.Msg)
+		case <-time.After(time.Second):
+			t.Fatal("subscription did not close")
+		}
+	}
+
+	require.Empty(t, sspy.Events.BindingStats())
+}
@@ -0,0 +1,18 @@
+package securityspy
+
+// SubscribeBuffer is the channel buffer size for channels returned by Events.Subscribe.
+const SubscribeBuffer = 100
+
+// Filter picks the events a subscription from Events.Subscribe receives. Empty fields
+// match everything. An event must match every field that is set. Within a field, any
+// listed value matches, and Cameras, CameraNames and Groups count as one field.
+type Filter struct {
+	Types       []EventType    // Event types to match. EventAllEvents is not special here.
+	Cameras     []int          // Camera numbers to match. Events without a camera never match a camera filter.
+	CameraNames []string       // Camera names to match.
+	Groups      []string       // Camera group names to match. Membership comes from the current Snapshot.
+	Reasons     []TriggerEvent // Trigger reasons to match. Only trigger events have reasons.
+	// MinScores matches CLASSIFY events with a score at or above the minimum for any listed
+	// class, ie. {"HUMAN": 80}. Other events do not match when this is set.
+	MinScores map[string]int
+}
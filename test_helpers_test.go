@@ -7,9 +7,11 @@ import (
 	"slices"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"golift.io/securityspy/v2"
+	"golift.io/securityspy/v2/securityspytest"
 	"golift.io/securityspy/v2/server"
 )
 
@@ -92,3 +94,19 @@ func testServerWithCamera(t *testing.T) (*securityspy.Server, *requestRecorder,
 
 	return serverObj, recorder, camera
 }
+
+// watchFake returns a server connected to a fake, watching its event stream.
+func watchFake(t *testing.T) *securityspy.Server {
+	t.Helper()
+
+	fake := securityspytest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sspy, err := securityspy.New(fake.Config())
+	require.NoError(t, err)
+
+	sspy.Events.Watch(10*time.Millisecond, false)
+	t.Cleanup(func() { sspy.Events.Stop(false) })
+
+	return sspy
+}
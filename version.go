@@ -0,0 +1,84 @@
+package securityspy
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrVersionParse is returned by ParseVersion when the text does not start with a number.
+var ErrVersionParse = errors.New("invalid version")
+
+// Version is a parsed SecuritySpy version, ie. 6.20 or 5.5.4. Compare versions with Compare
+// or AtLeast; minor versions are numbers, so 6.20 is newer than 6.3.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Raw   string // The text the version was parsed from.
+}
+
+// ParseVersion parses a dotted version string. Anything after the last number
+// in a part is ignored, so "6.1b2" parses as 6.1.
+func ParseVersion(text string) (Version, error) {
+	version := Version{Raw: text}
+	parts := strings.SplitN(strings.TrimSpace(text), ".", 3) //nolint:mnd // major.minor.patch
+
+	for idx, field := range []*int{&version.Major, &version.Minor, &version.Patch} {
+		if idx >= len(parts) {
+			break
+		}
+
+		digits := strings.IndexFunc(parts[idx], func(r rune) bool { return r < '0' || r > '9' })
+		if digits < 0 {
+			digits = len(parts[idx])
+		}
+
+		num, err := strconv.Atoi(parts[idx][:digits])
+		if err != nil && idx == 0 {
+			return Version{Raw: text}, fmt.Errorf("%w: %q", ErrVersionParse, text)
+		} else if err != nil {
+			break
+		}
+
+		*field = num
+
+		if digits < len(parts[idx]) {
+			break
+		}
+	}
+
+	return version, nil
+}
+
+// Compare returns -1, 0 or +1 when v is older than, the same as or newer than other.
+func (v Version) Compare(other Version) int {
+	return cmp.Or(cmp.Compare(v.Major, other.Major), cmp.Compare(v.Minor, other.Minor), cmp.Compare(v.Patch, other.Patch))
+}
+
+// AtLeast returns true if v is the same as or newer than major.minor.
+func (v Version) AtLeast(major, minor int) bool {
+	return v.Compare(Version{Major: major, Minor: minor}) >= 0
+}
+
+// IsZero returns true if the version is unknown, ie. before the first Refresh.
+func (v Version) IsZero() bool {
+	return v.Major == 0 && v.Minor == 0 && v.Patch == 0
+}
+
+// String returns the version as major.minor, with .patch if it is not 0.
+func (v Version) String() string {
+	if v.Patch != 0 {
+		return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	}
+
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// ParsedVersion returns Version as a comparable Version. It is zero if Version is empty or not a number.
+func (s *ServerInfo) ParsedVersion() Version {
+	version, _ := ParseVersion(s.Version)
+	return version
+}